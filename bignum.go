@@ -0,0 +1,181 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// ratDigits is the number of decimal places a big.Rat without a finite
+// decimal representation is rounded to, in addition to its non-repeating part.
+const ratDigits = 30
+
+func isBigNumber(t reflect.Type) bool {
+	return t == bigIntType || t == bigFloatType || t == bigRatType
+}
+
+// setIntText stores the integer text s in o. Values beyond the range of
+// int64 are stored in big numbers, floats and empty interfaces, which
+// receive a *big.Int, and are reported as TypeError for any other target.
+func setIntText(o reflect.Value, s string) error {
+	v := indirect(o)
+	if isBigNumber(v.Type()) {
+		return setBigText(v, integerTag, s)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return setInt(v, i)
+	}
+	if err.(*strconv.NumError).Err != strconv.ErrRange {
+		return err
+	}
+
+	b, _ := new(big.Int).SetString(s, 10)
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if b.IsUint64() && !v.OverflowUint(b.Uint64()) {
+			v.SetUint(b.Uint64())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		f, _ := new(big.Float).SetInt(b).Float64()
+		return setFloat(v, f)
+	default:
+		if isEmptyInterface(v) {
+			v.Set(reflect.ValueOf(b))
+			return nil
+		}
+	}
+	return &TypeError{Value: string(integerTag) + " " + s, Type: v.Type()}
+}
+
+// setFloatText stores the decimal text s in o.
+func setFloatText(o reflect.Value, s string) error {
+	v := indirect(o)
+	if isBigNumber(v.Type()) {
+		return setBigText(v, doubleTag, s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			return &TypeError{Value: string(doubleTag) + " " + s, Type: v.Type()}
+		}
+		return err
+	}
+	return setFloat(v, f)
+}
+
+// setBigText parses the text s of the XML-RPC type t into the big number v.
+func setBigText(v reflect.Value, t tag, s string) error {
+	ok := false
+	switch x := v.Addr().Interface().(type) {
+	case *big.Int:
+		_, ok = x.SetString(s, 10)
+	case *big.Float:
+		if x.Prec() == 0 {
+			x.SetPrec(decimalPrec(s))
+		}
+		_, _, err := x.Parse(s, 10)
+		ok = err == nil
+	case *big.Rat:
+		_, ok = x.SetString(s)
+	}
+	if !ok {
+		return &TypeError{Value: string(t) + " " + s, Type: v.Type()}
+	}
+	return nil
+}
+
+// decimalPrec returns the mantissa bits needed to hold the digits of the
+// decimal s, but at least the 64 bits big.Float uses by default.
+func decimalPrec(s string) uint {
+	// log2(10) < 3.33 bits per digit
+	if prec := uint(len(s)) * 10 / 3; prec > 64 {
+		return prec
+	}
+	return 64
+}
+
+// writeBigNumber writes o if it is a big number or a pointer to one and
+// reports whether it did. Nil pointers are written like other nil pointers.
+func (this *Encoder) writeBigNumber(o interface{}) bool {
+	switch n := o.(type) {
+	case *big.Int:
+		if n == nil {
+			this.writeNilOf(reflect.TypeOf(n))
+		} else {
+			this.writeBigInt(n)
+		}
+	case *big.Float:
+		if n == nil {
+			this.writeNilOf(reflect.TypeOf(n))
+		} else {
+			this.writeBigFloat(n)
+		}
+	case *big.Rat:
+		if n == nil {
+			this.writeNilOf(reflect.TypeOf(n))
+		} else {
+			this.writeBigRat(n)
+		}
+	case big.Int:
+		this.writeBigInt(&n)
+	case big.Float:
+		this.writeBigFloat(&n)
+	case big.Rat:
+		this.writeBigRat(&n)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeBigInt writes i as <ex:biginteger> with Extensions and as <string>
+// otherwise, as it may exceed the range of <int>.
+func (this *Encoder) writeBigInt(i *big.Int) {
+	t := stringTag
+	if this.Extensions {
+		t = exBigIntegerTag.ext()
+	}
+	this.openTag(t)
+	this.writeRaw(i.String())
+	this.closeTag(t)
+}
+
+// writeBigFloat writes f with all its digits as <ex:bigdecimal> with Extensions and as <double> otherwise.
+func (this *Encoder) writeBigFloat(f *big.Float) {
+	if f.IsInf() {
+		if this.err == nil {
+			this.err = fmt.Errorf("xmlrpc: cannot encode infinite big.Float")
+		}
+		return
+	}
+	this.writeDecimal(f.Text('f', -1))
+}
+
+// writeBigRat writes r like writeBigFloat. Fractions without a finite decimal
+// representation are rounded.
+func (this *Encoder) writeBigRat(r *big.Rat) {
+	n, exact := r.FloatPrec()
+	if !exact {
+		n += ratDigits
+	}
+	this.writeDecimal(r.FloatString(n))
+}
+
+func (this *Encoder) writeDecimal(s string) {
+	t := doubleTag
+	if this.Extensions {
+		t = exBigDecimalTag.ext()
+	}
+	this.openTag(t)
+	this.writeRaw(s)
+	this.closeTag(t)
+}
@@ -0,0 +1,162 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalBigNumbers(t *testing.T) {
+	s := `<?xml version="1.0"?>
+		<methodResponse xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions">
+		  <params>
+		    <param><value><ex:biginteger>123456789012345678901234567890</ex:biginteger></value></param>
+		    <param><value><ex:bigdecimal>0.1000000000000000000000000001</ex:bigdecimal></value></param>
+		    <param><value><double>0.25</double></value></param>
+		    <param><value><string>-98765432109876543210</string></value></param>
+		    <param><value><int>42</int></value></param>
+		    <param><value><ex:biginteger>7</ex:biginteger></value></param>
+		    <param><value><ex:bigdecimal>1.5</ex:bigdecimal></value></param>
+		  </params>
+		</methodResponse>`
+
+	var (
+		i      *big.Int
+		f      big.Float
+		r      *big.Rat
+		s2     big.Int
+		i2     big.Rat
+		gi, gf interface{}
+	)
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &i, &f, &r, &s2, &i2, &gi, &gf); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if i.String() != "123456789012345678901234567890" {
+		t.Errorf("unexpected big.Int %s", i)
+	}
+	if f.Text('f', -1) != "0.1000000000000000000000000001" {
+		t.Errorf("unexpected big.Float %s", f.Text('f', -1))
+	}
+	if r.RatString() != "1/4" || s2.String() != "-98765432109876543210" || i2.RatString() != "42" {
+		t.Errorf("unexpected values %s %s %s", r.RatString(), s2.String(), i2.RatString())
+	}
+	if n, ok := gi.(*big.Int); !ok || n.Int64() != 7 {
+		t.Errorf("expected *big.Int got %#v", gi)
+	}
+	if n, ok := gf.(*big.Float); !ok || n.String() != "1.5" {
+		t.Errorf("expected *big.Float got %#v", gf)
+	}
+}
+
+func TestUnmarshalOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		xml string
+		v   interface{}
+	}{
+		{"<int>300</int>", new(int8)},
+		{"<int>-1</int>", new(uint)},
+		{"<i8>70000</i8>", new(uint16)},
+		{"<ex:i8>9223372036854775808</ex:i8>", new(int64)},
+		{"<ex:biginteger>18446744073709551616</ex:biginteger>", new(uint64)},
+		{"<double>1e300</double>", new(float32)},
+		{"<double>1e400</double>", new(float64)},
+		{"<double>1.5</double>", new(big.Int)},
+	} {
+		s := "<methodResponse><params><param><value>" + tt.xml + "</value></param></params></methodResponse>"
+		var typeErr *TypeError
+		if err := UnmarshalResponse(strings.NewReader(s), tt.v); !errors.As(err, &typeErr) {
+			t.Errorf("%s: expected TypeError got %v", tt.xml, err)
+		}
+	}
+
+	var u uint64
+	s := "<methodResponse><params><param><value><ex:i8>18446744073709551615</ex:i8></value></param></params></methodResponse>"
+	if err := UnmarshalResponse(strings.NewReader(s), &u); err != nil || u != 18446744073709551615 {
+		t.Errorf("unexpected value %d err:%v", u, err)
+	}
+	var g interface{}
+	if err := UnmarshalResponse(strings.NewReader(s), &g); err != nil || g.(*big.Int).String() != "18446744073709551615" {
+		t.Errorf("unexpected value %v err:%v", g, err)
+	}
+}
+
+func TestMarshalBigNumbers(t *testing.T) {
+	i, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	f, _, _ := big.ParseFloat("0.1000000000000000000000000001", 10, 128, big.ToNearestEven)
+	args := []interface{}{i, f, big.NewRat(1, 8), big.NewRat(1, 3)}
+
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test.method", args...); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		"<string>123456789012345678901234567890</string>",
+		"<double>0.1000000000000000000000000001</double>",
+		"<double>0.125</double>",
+		"<double>0.333333333333333333333333333333</double>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %s in %s", want, s)
+		}
+	}
+
+	buf.Reset()
+	e := NewEncoder(buf)
+	e.Extensions = true
+	if err := e.EncodeCall("test.method", args[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	s = buf.String()
+	if !strings.Contains(s, "<ex:biginteger>123456789012345678901234567890</ex:biginteger>") ||
+		!strings.Contains(s, "<ex:bigdecimal>0.1000000000000000000000000001</ex:bigdecimal>") {
+		t.Errorf("unexpected extension types in %s", s)
+	}
+
+	var ri *big.Int
+	var rf *big.Float
+	if _, err := UnmarshalCall(strings.NewReader(s), &ri, &rf); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if ri.Cmp(i) != 0 || rf.Text('f', -1) != f.Text('f', -1) {
+		t.Errorf("expected %s %s got %s %s", i, f.Text('f', -1), ri, rf.Text('f', -1))
+	}
+
+	if err := Marshal(buf, "test.method", new(big.Float).SetInf(false)); err == nil {
+		t.Errorf("expected error encoding infinity")
+	}
+}
+
+func TestMarshalBigNumberNilAndValues(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test.method", (*big.Int)(nil), (*big.Float)(nil), (*big.Rat)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<value><nil/></value>"); n != 3 {
+		t.Errorf("expected 3 nil values in %s", buf.String())
+	}
+
+	v := struct {
+		I big.Int   `xmlrpc:"i"`
+		F big.Float `xmlrpc:"f"`
+		R big.Rat   `xmlrpc:"r"`
+	}{}
+	v.I.SetInt64(42)
+	v.F.SetFloat64(1.5)
+	v.R.SetFrac64(1, 4)
+	buf.Reset()
+	if err := Marshal(buf, "test.method", v); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<name>i</name><value><string>42</string></value>",
+		"<name>f</name><value><double>1.5</double></value>",
+		"<name>r</name><value><double>0.25</double></value>",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in %s", want, buf.String())
+		}
+	}
+}
@@ -2,56 +2,145 @@ package xmlrpc
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 )
 
 type Client interface {
 	Call(method string, args ...interface{}) (interface{}, error)
+	// CallContext is like Call but aborts the request and the decoding
+	// of the response when ctx is canceled or its deadline expires.
+	CallContext(ctx context.Context, method string, args ...interface{}) (interface{}, error)
+	// CallInto calls method with args and decodes the single response param
+	// into the value pointed to by result like UnmarshalResponse.
+	// A nil result discards the response. A fault is returned as a *Fault error.
+	CallInto(result interface{}, method string, args ...interface{}) error
+	// CallIntoContext is like CallInto with the cancellation of CallContext.
+	CallIntoContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// Call calls method on c and returns the single response param decoded into a T.
+func Call[T any](c Client, method string, args ...interface{}) (T, error) {
+	var result T
+	err := c.CallInto(&result, method, args...)
+	return result, err
 }
 
 type clientImpl struct {
 	client *http.Client
 	url    *url.URL
+	header http.Header
+	limits Limits
+	mode   Mode
 }
 
-func NewClient(url *url.URL) (Client, error) {
-	return &clientImpl{client: new(http.Client), url: url}, nil
+// NewClient returns a client calling the XML-RPC endpoint url configured by opts.
+func NewClient(url *url.URL, opts ...ClientOption) (Client, error) {
+	cfg := clientConfig{header: make(http.Header)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	client, err := cfg.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	return &clientImpl{client: client, url: url, header: cfg.header, limits: cfg.limits, mode: cfg.mode}, nil
 }
 
+// Call calls method with args and returns the response decoded like Unmarshal.
+// A fault response is returned as a *Fault error.
 func (this *clientImpl) Call(method string, args ...interface{}) (interface{}, error) {
+	return this.CallContext(context.Background(), method, args...)
+}
+
+func (this *clientImpl) CallContext(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	var res interface{}
+	err := this.do(ctx, method, args, func(r io.Reader) error {
+		return this.newDecoder(r).read(&res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fault, ok := res.(map[string]interface{})[string(faultTag)]; ok {
+		return nil, newFault(fault)
+	}
+	return res, nil
+}
+
+func (this *clientImpl) CallInto(result interface{}, method string, args ...interface{}) error {
+	return this.CallIntoContext(context.Background(), result, method, args...)
+}
+
+func (this *clientImpl) CallIntoContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return this.do(ctx, method, args, func(r io.Reader) error {
+		if result == nil {
+			return this.newDecoder(r).DecodeResponse()
+		}
+		return this.newDecoder(r).DecodeResponse(result)
+	})
+}
+
+func (this *clientImpl) newDecoder(r io.Reader) *Decoder {
+	d := NewDecoder(r)
+	d.Limits = this.limits
+	d.Mode = this.mode
+	return d
+}
+
+// do posts the call of method with args and reads the response body with decode.
+func (this *clientImpl) do(ctx context.Context, method string, args []interface{}, decode func(io.Reader) error) error {
 
 	var (
 		err  error
+		req  *http.Request
 		resp *http.Response
-		res  interface{}
 	)
 
 	buf := new(bytes.Buffer)
 	err = Marshal(buf, method, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// keep-alive is handled by the transport layer
-	resp, err = http.Post(this.url.String(), "text/xml", buf)
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, this.url.String(), buf)
 	if err != nil {
-		return nil, fmt.Errorf("error calling rpc endpoint: %v", err)
+		return fmt.Errorf("error creating rpc request: %w", err)
 	}
-	var b []byte
-	b, err = ioutil.ReadAll(resp.Body)
-	fmt.Printf("Resp: %s\n", string(b))
-	if err != nil {
-		return nil, fmt.Errorf("error reading rpc result body: %v", err)
+	for key, values := range this.header {
+		req.Header[key] = values
 	}
-	err = Unmarshal(resp.Body, &res)
+	req.Header.Set("Content-Type", "text/xml")
 
-	if resp.Close {
-		// ignore error
-		resp.Body.Close()
+	// keep-alive is handled by the transport layer
+	resp, err = this.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling rpc endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error calling rpc endpoint: %s", resp.Status)
 	}
+	if err = decode(&contextReader{ctx: ctx, r: resp.Body}); err != nil {
+		if fault, ok := err.(*Fault); ok {
+			return fault
+		}
+		return fmt.Errorf("error reading rpc result body: %w", err)
+	}
+	return nil
+}
 
-	return res, err
+// contextReader stops reading as soon as its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (this *contextReader) Read(p []byte) (int, error) {
+	if err := this.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return this.r.Read(p)
 }
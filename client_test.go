@@ -0,0 +1,178 @@
+package xmlrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, srv *Server, opts ...ClientOption) (Client, func()) {
+	ts := httptest.NewServer(srv)
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(u, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts.Close
+}
+
+func TestClientCall(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("math.add", func(a, b int) int { return a + b })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	res, err := c.Call("math.add", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params := res.(map[string]interface{})["params"].([]interface{}); params[0] != int64(5) {
+		t.Errorf("expected %d got %v", 5, params[0])
+	}
+}
+
+func TestClientFault(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("fail", func() error { return &Fault{Code: 42, String: "out of coffee"} })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	_, err := c.Call("fail")
+	var fault *Fault
+	if !errors.As(err, &fault) {
+		t.Fatalf("expected *Fault got %T %v", err, err)
+	}
+	if fault.Code != 42 || fault.String != "out of coffee" || fault.Members["faultCode"] != int64(42) {
+		t.Errorf("unexpected fault %+v", fault)
+	}
+
+	_, err = c.Call("missing")
+	if !errors.As(err, &fault) || fault.Code != FaultMethodNotFound {
+		t.Errorf("expected fault %d got %v", FaultMethodNotFound, err)
+	}
+}
+
+func TestClientCallContext(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("hang", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.CallContext(ctx, "hang")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("call returned after %v", d)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CallContext(ctx, "hang"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &contextReader{ctx: ctx, r: strings.NewReader("<methodResponse>")}
+	cancel()
+	var o interface{}
+	if err := Unmarshal(r, &o); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("ping", func() string { return "pong" })
+	var header http.Header
+	var peerCerts int
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		peerCerts = len(r.TLS.PeerCertificates)
+		srv.ServeHTTP(w, r)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	c, err := NewClient(u,
+		WithHTTPClient(&http.Client{}),
+		WithHeader("X-Api-Key", "secret"),
+		WithUserAgent("xmlrpc-test/1.0"),
+		WithTimeout(5*time.Second),
+		WithTLSConfig(&tls.Config{RootCAs: roots, Certificates: ts.TLS.Certificates}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Call("ping"); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Api-Key") != "secret" || header.Get("User-Agent") != "xmlrpc-test/1.0" {
+		t.Errorf("unexpected header %v", header)
+	}
+	if peerCerts != 1 {
+		t.Errorf("expected client certificate got %d", peerCerts)
+	}
+
+	// without the TLS config the server certificate is unknown
+	c, _ = NewClient(u)
+	if _, err := c.Call("ping"); err == nil {
+		t.Errorf("expected TLS error")
+	}
+
+	if _, err := NewClient(u, WithHTTPClient(&http.Client{Transport: roundTripper(nil)}), WithTLSConfig(&tls.Config{})); err == nil {
+		t.Errorf("expected error for custom transport")
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (this roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return this(r)
+}
+
+func TestClientCallInto(t *testing.T) {
+	srv := NewServer()
+	srv.Register("blog", &blogService{posts: map[int]post{1: {1, "Hello"}}})
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var p post
+	if err := c.CallInto(&p, "blog.getPost", 1); err != nil || p.Title != "Hello" {
+		t.Errorf("unexpected post %+v err:%v", p, err)
+	}
+	if err := c.CallInto(nil, "blog.getPost", 1); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	count, err := Call[int](c, "blog.countPosts")
+	if err != nil || count != 1 {
+		t.Errorf("unexpected count %d err:%v", count, err)
+	}
+	_, err = Call[post](c, "blog.getPost", 2)
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Code != 404 {
+		t.Errorf("expected fault %d got %v", 404, err)
+	}
+}
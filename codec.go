@@ -1,14 +1,19 @@
 package xmlrpc
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type tag string
@@ -29,49 +34,170 @@ const (
 	doubleTag         tag = "double"
 	integerTag        tag = "int"
 	integerTag2       tag = "i4"
+	i8Tag             tag = "i8"
 	stringTag         tag = "string"
 	structTag         tag = "struct"
 	memberTag         tag = "member"
 	nilTag            tag = "nil"
 	faultTag          tag = "fault"
-	iso8601Format         = "20060102T15:04:5"
+	iso8601Format         = "20060102T15:04:05"
 )
 
+// Marshal writes a <methodCall> document for method with args as params to w.
 func Marshal(w io.Writer, method string, args ...interface{}) error {
+	return NewEncoder(w).EncodeCall(method, args...)
+}
 
-	c := encoder{w: w}
-	io.WriteString(w, xml.Header)
-	openTag(w, methodCallTag)
-	openTag(w, methodNameTag)
-	io.WriteString(w, method)
-	closeTag(w, methodNameTag)
-	openTag(w, paramsTag)
-	for _, o := range args {
-		openTag(w, paramTag)
-		c.write(o)
-		closeTag(w, paramTag)
-	}
-	closeTag(w, paramsTag)
-	closeTag(w, methodCallTag)
-	return nil
+// A Decoder reads XML-RPC documents from an input stream.
+// Several documents can be read from the same stream one after another.
+type Decoder struct {
+	// CharsetReader, if non-nil, converts input in a character set
+	// other than UTF-8, see xml.Decoder.CharsetReader.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+	// Location is the time zone of dateTime values without a zone offset,
+	// nil means UTC.
+	Location *time.Location
+
+	// Limits restricts the size of the documents read, the zero value imposes no limits.
+	Limits Limits
+
+	// Mode selects how strictly documents have to follow the specification.
+	Mode Mode
+
+	d     *xml.Decoder
+	in    *limitReader // input of d, nil for decoders replaying tokens
+	depth int          // nesting of the value being decoded
+	path  []string     // path of the value being decoded, see push
 }
 
-type decoder struct {
-	d *xml.Decoder
+// MarshalResponse writes a <methodResponse> document with v as its single param to w.
+func MarshalResponse(w io.Writer, v interface{}) error {
+	return NewEncoder(w).EncodeResponse(v)
 }
 
+// MarshalFault writes a <methodResponse> document holding a fault
+// with the given faultCode and faultString to w.
+func MarshalFault(w io.Writer, code int, message string) error {
+	return NewEncoder(w).EncodeFault(code, message)
+}
+
+// Unmarshal reads a <methodResponse> or <methodCall> document from r and stores
+// it generically as a map[string]interface{} in the interface{} pointed to by o.
+// A response has the key "params" or "fault", a call the keys "methodName" and "params".
 func Unmarshal(r io.Reader, o interface{}) error {
-	d := newDecoder(r)
+	d := NewDecoder(r)
 	if err := d.read(o); err != nil {
 		return err
 	}
 	return nil
 }
-func newDecoder(r io.Reader) decoder {
-	return decoder{d: xml.NewDecoder(r)}
+
+// UnmarshalResponse reads a <methodResponse> from r and decodes its params into v.
+// The i'th param is stored in the value pointed to by v[i], which may be a pointer
+// to a struct, slice, array, map with string keys, time.Time, []byte, a basic type
+// or an empty interface. Params without a corresponding element in v are skipped.
+// A fault response is returned as a *Fault error.
+func UnmarshalResponse(r io.Reader, v ...interface{}) error {
+	return NewDecoder(r).DecodeResponse(v...)
+}
+
+// UnmarshalCall reads a <methodCall> document from r, decodes its params
+// into v like UnmarshalResponse and returns the method name.
+func UnmarshalCall(r io.Reader, v ...interface{}) (string, error) {
+	d := NewDecoder(r)
+	method, err := d.DecodeCall()
+	if err != nil {
+		return "", err
+	}
+	return method, d.DecodeParams(v...)
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	this := &Decoder{}
+	this.in = &limitReader{r: bufio.NewReader(r), d: this}
+	this.d = xml.NewDecoder(this.in)
+	return this
 }
 
-func (this *decoder) read(o interface{}) error {
+// DecodeResponse reads the next <methodResponse> document and decodes
+// its params into v like UnmarshalResponse.
+func (this *Decoder) DecodeResponse(v ...interface{}) error {
+	this.d.CharsetReader = this.CharsetReader
+	this.startDocument()
+	return this.readResponse(v)
+}
+
+// DecodeCall reads the next <methodCall> document up to its method name and
+// returns the name. The params have to be read with DecodeParams afterwards,
+// which allows choosing their types depending on the method.
+func (this *Decoder) DecodeCall() (name string, err error) {
+	defer this.annotate(&err)
+	this.d.CharsetReader = this.CharsetReader
+	this.startDocument()
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(methodCallTag):
+				return this.decodeMethodName()
+			}
+		}
+	}
+	return "", fmt.Errorf("this point shouldn't be reached")
+}
+
+// DecodeParams reads the params of the <methodCall> started with DecodeCall
+// into v like UnmarshalResponse and consumes the rest of the document.
+func (this *Decoder) DecodeParams(v ...interface{}) error {
+	dst, err := paramTargets(v)
+	if err != nil {
+		return err
+	}
+	_, err = this.decodeCallParams(dst)
+	return err
+}
+
+// decodeCallParams decodes the params of a methodCall into dst
+// and returns the number of params found.
+func (this *Decoder) decodeCallParams(dst []reflect.Value) (n int, err error) {
+	defer this.annotate(&err)
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return n, err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(paramsTag):
+				if n, err = this.decodeParamList(dst); err != nil {
+					return n, err
+				}
+			default:
+				if err := this.unknownElement(v, methodCallTag); err != nil {
+					return n, err
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == string(methodCallTag) {
+				return n, nil
+			} else {
+				return n, fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, methodCallTag)
+			}
+		}
+	}
+	return n, fmt.Errorf("this point shouldn't be reached")
+}
+
+func (this *Decoder) read(o interface{}) (err error) {
+	defer this.annotate(&err)
+	this.startDocument()
 
 	m := reflect.ValueOf(make(map[string]interface{}))
 	value := reflect.ValueOf(o)
@@ -90,13 +216,50 @@ func (this *decoder) read(o interface{}) error {
 				} else {
 					return nil
 				}
+			case string(methodCallTag):
+				return this.decodeMethodCall(m)
+			}
+		}
+	}
+	return fmt.Errorf("this point shouldn't be reached")
+}
+
+// paramTargets returns the values pointed to by v.
+func paramTargets(v []interface{}) ([]reflect.Value, error) {
+	dst := make([]reflect.Value, len(v))
+	for i, o := range v {
+		rv := reflect.ValueOf(o)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return nil, fmt.Errorf("xmlrpc: cannot unmarshal into non-pointer or nil %T", o)
+		}
+		dst[i] = rv.Elem()
+	}
+	return dst, nil
+}
+
+func (this *Decoder) readResponse(v []interface{}) (err error) {
+	dst, err := paramTargets(v)
+	if err != nil {
+		return err
+	}
+	defer this.annotate(&err)
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(methodResponseTag):
+				return this.decodeTypedResponse(dst)
 			}
 		}
 	}
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeMethodResponse(o reflect.Value) error {
+func (this *Decoder) decodeMethodCall(o reflect.Value) error {
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -108,17 +271,114 @@ func (this *decoder) decodeMethodResponse(o reflect.Value) error {
 		switch v := t.(type) {
 		case xml.StartElement:
 			switch v.Name.Local {
+			case string(methodNameTag):
+				name, err := this.readText()
+				if err != nil {
+					return err
+				}
+				o.SetMapIndex(reflect.ValueOf(string(methodNameTag)), reflect.ValueOf(name))
+
 			case string(paramsTag):
 				if err := this.decodeParams(nVal); err != nil {
 					return err
 				}
 				o.SetMapIndex(reflect.ValueOf(string(paramsTag)), nVal)
+			default:
+				if err := this.unknownElement(v, methodCallTag); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			switch v.Name.Local {
+			case string(methodCallTag):
+				return nil
+			case string(methodNameTag):
+				// continue
+			default:
+				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, methodCallTag)
+			}
+		}
+	}
+	return fmt.Errorf("this point shouldn't be reached")
+}
+
+func (this *Decoder) decodeMethodResponse(o reflect.Value) error {
+	params := 0
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return err
+		}
+		var n interface{}
+		nVal := reflect.ValueOf(&n).Elem()
+
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(paramsTag):
+				params++
+				if params > 1 && this.Mode == StrictMode {
+					return fmt.Errorf("xmlrpc: response with more than one params element")
+				}
+				if err := this.decodeParams(nVal); err != nil {
+					return err
+				}
+				o.SetMapIndex(reflect.ValueOf(string(paramsTag)), nVal)
 
 			case string(faultTag):
 				if err := this.decodeFault(nVal); err != nil {
 					return err
 				}
 				o.SetMapIndex(reflect.ValueOf(string(faultTag)), nVal)
+			default:
+				if err := this.unknownElement(v, methodResponseTag); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == string(methodResponseTag) {
+				return nil
+			} else {
+				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, methodResponseTag)
+			}
+		}
+	}
+	return fmt.Errorf("this point shouldn't be reached")
+}
+
+// decodeTypedResponse decodes the params of a methodResponse into dst.
+// A fault is returned as a *Fault error.
+func (this *Decoder) decodeTypedResponse(dst []reflect.Value) error {
+	params := 0
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(paramsTag):
+				params++
+				if params > 1 && this.Mode == StrictMode {
+					return fmt.Errorf("xmlrpc: response with more than one params element")
+				}
+				if _, err := this.decodeParamList(dst); err != nil {
+					return err
+				}
+			case string(faultTag):
+				var n interface{}
+				if err := this.decodeFault(reflect.ValueOf(&n).Elem()); err != nil {
+					return err
+				}
+				if err := this.d.Skip(); err != nil {
+					return err
+				}
+				return newFault(n)
+			default:
+				if err := this.unknownElement(v, methodResponseTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			if v.Name.Local == string(methodResponseTag) {
@@ -131,7 +391,33 @@ func (this *decoder) decodeMethodResponse(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeParams(o reflect.Value) error {
+func (this *Decoder) decodeMethodName() (string, error) {
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(methodNameTag):
+				name, err := this.readText()
+				if err != nil {
+					return "", err
+				}
+				if name == "" {
+					return "", fmt.Errorf("xmlrpc: empty method name")
+				}
+				return name, nil
+			}
+		case xml.EndElement:
+			return "", fmt.Errorf("got xml.EndElement %s expected xml.StartElement %s", v.Name.Local, methodNameTag)
+		}
+	}
+	return "", fmt.Errorf("this point shouldn't be reached")
+}
+
+func (this *Decoder) decodeParams(o reflect.Value) error {
 	arr := make([]interface{}, 0)
 	for {
 		t, err := this.d.Token()
@@ -142,12 +428,21 @@ func (this *decoder) decodeParams(o reflect.Value) error {
 		case xml.StartElement:
 			switch v.Name.Local {
 			case string(paramTag):
+				if err := this.checkMembers(len(arr) + 1); err != nil {
+					return err
+				}
 				var n interface{}
 				nVal := reflect.ValueOf(&n).Elem()
+				this.push(fmt.Sprintf("params[%d]", len(arr)))
 				if err := this.decodeParam(nVal); err != nil {
 					return err
 				}
+				this.pop()
 				arr = append(arr, n)
+			default:
+				if err := this.unknownElement(v, paramsTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			switch v.Name.Local {
@@ -164,7 +459,51 @@ func (this *decoder) decodeParams(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeArray(o reflect.Value) error {
+// decodeParamList decodes the i'th param into dst[i], skips params beyond len(dst)
+// and returns the number of params.
+func (this *Decoder) decodeParamList(dst []reflect.Value) (int, error) {
+	i := 0
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return i, err
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case string(paramTag):
+				if err := this.checkMembers(i + 1); err != nil {
+					return i, err
+				}
+				this.push(fmt.Sprintf("params[%d]", i))
+				if i < len(dst) {
+					err = this.decodeParam(dst[i])
+				} else {
+					err = this.d.Skip()
+				}
+				if err != nil {
+					return i, err
+				}
+				this.pop()
+				i++
+			default:
+				if err := this.unknownElement(v, paramsTag); err != nil {
+					return i, err
+				}
+			}
+		case xml.EndElement:
+			switch v.Name.Local {
+			case string(paramsTag):
+				return i, nil
+			default:
+				return i, fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, paramsTag)
+			}
+		}
+	}
+	return i, fmt.Errorf("this point shouldn't be reached")
+}
+
+func (this *Decoder) decodeArray(o reflect.Value) error {
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -177,6 +516,10 @@ func (this *decoder) decodeArray(o reflect.Value) error {
 				if err := this.decodeData(o); err != nil {
 					return err
 				}
+			default:
+				if err := this.unknownElement(v, arrayTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			switch v.Name.Local {
@@ -190,38 +533,74 @@ func (this *decoder) decodeArray(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeData(o reflect.Value) error {
-	arr := make([]interface{}, 0)
+func (this *Decoder) decodeData(o reflect.Value) error {
+	v := indirect(o)
+	arr, generic := v, false
+	switch {
+	case v.Kind() == reflect.Slice:
+		if v.IsNil() {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		} else {
+			v.SetLen(0)
+		}
+	case v.Kind() == reflect.Array:
+	case isEmptyInterface(v):
+		// decode into a fresh []interface{} and store it in v when done
+		arr, generic = reflect.ValueOf(&[]interface{}{}).Elem(), true
+	default:
+		return &TypeError{Value: string(arrayTag), Type: v.Type()}
+	}
+
+	i := 0
 	for {
 		t, err := this.d.Token()
 		if err != nil {
 			return err
 		}
-		switch v := t.(type) {
+		switch t := t.(type) {
 		case xml.StartElement:
-			switch v.Name.Local {
+			switch t.Name.Local {
 			case string(valueTag):
-				var n interface{}
-				nVal := reflect.ValueOf(&n).Elem()
-				if err := this.decodeValue(nVal); err != nil {
+				if err := this.checkMembers(i + 1); err != nil {
+					return err
+				}
+				if arr.Kind() == reflect.Slice {
+					arr.Set(reflect.Append(arr, reflect.Zero(arr.Type().Elem())))
+				} else if i >= arr.Len() {
+					return fmt.Errorf("xmlrpc: cannot unmarshal array with more than %d elements into Go value of type %s", arr.Len(), arr.Type())
+				}
+				this.push(fmt.Sprintf("[%d]", i))
+				if err := this.decodeValue(arr.Index(i)); err != nil {
+					return err
+				}
+				this.pop()
+				i++
+			default:
+				if err := this.unknownElement(t, dataTag); err != nil {
 					return err
 				}
-				arr = append(arr, n)
 			}
 		case xml.EndElement:
-			switch v.Name.Local {
+			switch t.Name.Local {
 			case string(dataTag):
-				o.Set(reflect.ValueOf(arr))
+				if arr.Kind() == reflect.Array {
+					for ; i < arr.Len(); i++ {
+						arr.Index(i).Set(reflect.Zero(arr.Type().Elem()))
+					}
+				}
+				if generic {
+					v.Set(arr)
+				}
 				return nil
 			default:
-				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, dataTag)
+				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", t.Name.Local, dataTag)
 			}
 		}
 	}
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeParam(o reflect.Value) error {
+func (this *Decoder) decodeParam(o reflect.Value) error {
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -234,6 +613,10 @@ func (this *decoder) decodeParam(o reflect.Value) error {
 				if err := this.decodeValue(o); err != nil {
 					return err
 				}
+			default:
+				if err := this.unknownElement(v, paramTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			switch v.Name.Local {
@@ -247,7 +630,7 @@ func (this *decoder) decodeParam(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeFault(o reflect.Value) error {
+func (this *Decoder) decodeFault(o reflect.Value) error {
 	var n interface{}
 	nVal := reflect.ValueOf(&n).Elem()
 
@@ -261,9 +644,15 @@ func (this *decoder) decodeFault(o reflect.Value) error {
 		case xml.StartElement:
 			switch v.Name.Local {
 			case string(valueTag):
+				this.push(string(faultTag))
 				if err := this.decodeValue(nVal); err != nil {
 					return err
 				}
+				this.pop()
+			default:
+				if err := this.unknownElement(v, faultTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			if v.Name.Local == string(faultTag) {
@@ -277,14 +666,37 @@ func (this *decoder) decodeFault(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeValue(o reflect.Value) error {
+func (this *Decoder) decodeValue(o reflect.Value) error {
+	defer this.leave()
+	if err := this.enter(); err != nil {
+		return err
+	}
+	if u := findUnmarshaler(o); u != nil {
+		return u.UnmarshalXMLRPC(this, xml.StartElement{Name: xml.Name{Local: string(valueTag)}})
+	}
+	// text directly inside <value> is an untyped string unless a type element follows,
+	// in which case it must be whitespace formatting the document
+	var text []byte
+	typed := false
 	for {
 		t, err := this.d.Token()
 		if err != nil {
 			return err
 		}
 		switch v := t.(type) {
+		case xml.CharData:
+			if text, err = this.appendText(text, v); err != nil {
+				return err
+			}
 		case xml.StartElement:
+			if typed || len(bytes.TrimSpace(text)) > 0 {
+				return fmt.Errorf("xmlrpc: unexpected element %s in value", v.Name.Local)
+			}
+			typed = true
+			if isExtension(v.Name) {
+				err = this.decodeExtension(o, v.Name.Local)
+				break
+			}
 			switch v.Name.Local {
 			case string(structTag):
 				err = this.decodeStruct(o)
@@ -292,6 +704,8 @@ func (this *decoder) decodeValue(o reflect.Value) error {
 				err = this.decodeInt(o)
 			case string(integerTag2):
 				err = this.decodeInt(o)
+			case string(i8Tag):
+				err = this.decodeInt(o)
 			case string(stringTag):
 				err = this.decodeString(o)
 			case string(doubleTag):
@@ -306,22 +720,30 @@ func (this *decoder) decodeValue(o reflect.Value) error {
 				err = this.decodeNil(o)
 			case string(arrayTag):
 				err = this.decodeArray(o)
+			default:
+				err = this.unknownElement(v, valueTag)
 			}
 		case xml.EndElement:
 			if v.Name.Local == string(valueTag) {
+				if !typed {
+					return setString(o, string(text))
+				}
+				if len(bytes.TrimSpace(text)) > 0 {
+					return fmt.Errorf("xmlrpc: unexpected text %q in value", bytes.TrimSpace(text))
+				}
 				return nil
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, valueTag)
 			}
 		}
 		if err != nil {
-			return fmt.Errorf("decodeValue got error: %v", err)
+			return err
 		}
 	}
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeNil(o reflect.Value) error {
+func (this *Decoder) decodeNil(o reflect.Value) error {
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -330,16 +752,18 @@ func (this *decoder) decodeNil(o reflect.Value) error {
 		switch v := t.(type) {
 		case xml.EndElement:
 			if v.Name.Local == string(nilTag) {
+				o.Set(reflect.Zero(o.Type()))
 				return nil
 			} else {
-				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, nil)
+				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, nilTag)
 			}
 		}
 	}
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeBase64(o reflect.Value) error {
+func (this *Decoder) decodeBase64(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -347,14 +771,17 @@ func (this *decoder) decodeBase64(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			if data, err := base64.StdEncoding.DecodeString(string(v)); err != nil {
-				return fmt.Errorf("errr parsing base64: %s", err)
-			} else {
-				o.Set(reflect.ValueOf(data))
+			if text, err = this.appendText(text, v); err != nil {
+				return err
 			}
 		case xml.EndElement:
 			if v.Name.Local == string(base64Tag) {
-				return nil
+				// base64 is often wrapped into lines
+				data, err := base64.StdEncoding.DecodeString(strings.Map(dropSpace, string(text)))
+				if err != nil {
+					return fmt.Errorf("errr parsing base64: %s", err)
+				}
+				return setBytes(o, data)
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, base64Tag)
 			}
@@ -363,7 +790,8 @@ func (this *decoder) decodeBase64(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeDate(o reflect.Value) error {
+func (this *Decoder) decodeDate(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -371,14 +799,16 @@ func (this *decoder) decodeDate(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			if date, err := time.Parse(iso8601Format, string(v)); err != nil {
-				return fmt.Errorf("errr parsing date: %s", err)
-			} else {
-				o.Set(reflect.ValueOf(date))
+			if text, err = this.appendText(text, v); err != nil {
+				return err
 			}
 		case xml.EndElement:
-			if v.Name.Local == string(dateTimeTag) {
-				return nil
+			if v.Name.Local == string(dateTimeTag) || v.Name.Local == string(exDateTimeTag) {
+				date, err := parseDateTime(string(text), this.Location)
+				if err != nil {
+					return err
+				}
+				return setTime(o, date)
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, dateTimeTag)
 			}
@@ -387,7 +817,8 @@ func (this *decoder) decodeDate(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeBoolean(o reflect.Value) error {
+func (this *Decoder) decodeBoolean(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -395,14 +826,19 @@ func (this *decoder) decodeBoolean(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			if string(v) == "1" {
-				o.Set(reflect.ValueOf(true))
-			} else {
-				o.Set(reflect.ValueOf(false))
+			if text, err = this.appendText(text, v); err != nil {
+				return err
 			}
 		case xml.EndElement:
 			if v.Name.Local == string(booleanTag) {
-				return nil
+				switch s := strings.TrimSpace(string(text)); {
+				case s == "1", this.Mode == LenientMode && strings.EqualFold(s, "true"):
+					return setBool(o, true)
+				case s == "0", this.Mode == LenientMode && strings.EqualFold(s, "false"):
+					return setBool(o, false)
+				default:
+					return fmt.Errorf("xmlrpc: invalid boolean %q", s)
+				}
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, booleanTag)
 			}
@@ -411,7 +847,8 @@ func (this *decoder) decodeBoolean(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeDouble(o reflect.Value) error {
+func (this *Decoder) decodeDouble(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -419,14 +856,12 @@ func (this *decoder) decodeDouble(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			if flo, err := strconv.ParseFloat(string(v), 64); err != nil {
+			if text, err = this.appendText(text, v); err != nil {
 				return err
-			} else {
-				o.Set(reflect.ValueOf(flo))
 			}
 		case xml.EndElement:
-			if v.Name.Local == string(doubleTag) {
-				return nil
+			if v.Name.Local == string(doubleTag) || v.Name.Local == string(exFloatTag) || v.Name.Local == string(exBigDecimalTag) {
+				return setFloatText(o, strings.TrimSpace(string(text)))
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, doubleTag)
 			}
@@ -435,7 +870,8 @@ func (this *decoder) decodeDouble(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeInt(o reflect.Value) error {
+func (this *Decoder) decodeInt(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -443,14 +879,16 @@ func (this *decoder) decodeInt(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			if intV, err := strconv.ParseInt(string(v), 10, 64); err != nil {
+			if text, err = this.appendText(text, v); err != nil {
 				return err
-			} else {
-				o.Set(reflect.ValueOf(intV))
 			}
 		case xml.EndElement:
-			if v.Name.Local == string(integerTag) || v.Name.Local == string(integerTag2) {
-				return nil
+			if isIntegerTag(v.Name.Local) {
+				s := strings.TrimSpace(string(text))
+				if s == "" && this.Mode == LenientMode {
+					s = "0"
+				}
+				return setIntText(o, s)
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, integerTag)
 			}
@@ -459,7 +897,8 @@ func (this *decoder) decodeInt(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeString(o reflect.Value) error {
+func (this *Decoder) decodeString(o reflect.Value) error {
+	var text []byte
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -467,10 +906,13 @@ func (this *decoder) decodeString(o reflect.Value) error {
 		}
 		switch v := t.(type) {
 		case xml.CharData:
-			o.Set(reflect.ValueOf(string(v)))
+			if text, err = this.appendText(text, v); err != nil {
+				return err
+			}
 		case xml.EndElement:
 			if v.Name.Local == string(stringTag) {
-				return nil
+				// strings keep their whitespace
+				return setString(o, string(text))
 			} else {
 				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, stringTag)
 			}
@@ -479,33 +921,64 @@ func (this *decoder) decodeString(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeStruct(o reflect.Value) error {
-	m := reflect.ValueOf(make(map[string]interface{}))
-	o.Set(m)
+func (this *Decoder) decodeStruct(o reflect.Value) error {
+	v := indirect(o)
+	switch {
+	case v.Kind() == reflect.Struct:
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case isEmptyInterface(v):
+		m := reflect.ValueOf(make(map[string]interface{}))
+		v.Set(m)
+		v = m
+	default:
+		return &TypeError{Value: string(structTag), Type: v.Type()}
+	}
+	members := 0
+	var seen map[string]bool
+	if this.Mode == StrictMode {
+		seen = make(map[string]bool)
+	}
 	for {
 		t, err := this.d.Token()
 		if err != nil {
 			return err
 		}
-		switch v := t.(type) {
+		switch t := t.(type) {
 		case xml.StartElement:
-			switch v.Name.Local {
+			switch t.Name.Local {
 			case string(memberTag):
-				this.decodeMember(m)
+				members++
+				if err := this.checkMembers(members); err != nil {
+					return err
+				}
+				if err := this.decodeMember(v, seen); err != nil {
+					return err
+				}
+			default:
+				if err := this.unknownElement(t, structTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
-			if v.Name.Local == string(structTag) {
+			if t.Name.Local == string(structTag) {
 				return nil
 			} else {
-				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", v.Name.Local, structTag)
+				return fmt.Errorf("got xml.EndElement %s expected xml.EndElement %s", t.Name.Local, structTag)
 			}
 		}
 	}
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-func (this *decoder) decodeMember(o reflect.Value) error {
+// decodeMember decodes a <member> into the matching field of the struct o
+// or into a new entry of the map o. Members without a matching field are skipped.
+// In StrictMode seen holds the names of the preceding members.
+func (this *Decoder) decodeMember(o reflect.Value, seen map[string]bool) error {
 	var name string
+	hasValue := false
 	for {
 		t, err := this.d.Token()
 		if err != nil {
@@ -515,22 +988,50 @@ func (this *decoder) decodeMember(o reflect.Value) error {
 		case xml.StartElement:
 			switch v.Name.Local {
 			case string(nameTag):
-				if name, err = this.readNextCharData(); err != nil {
+				if name, err = this.readText(); err != nil {
 					return err
 				}
 			case string(valueTag):
-				if name != "" {
-					var n interface{}
-					nVal := reflect.ValueOf(&n).Elem()
-					this.decodeValue(nVal)
-					o.SetMapIndex(reflect.ValueOf(name), nVal)
-				} else {
+				if name == "" {
 					return fmt.Errorf("got value element without name element before")
 				}
+				if seen != nil {
+					if seen[name] {
+						return fmt.Errorf("xmlrpc: duplicate struct member %s", name)
+					}
+					seen[name] = true
+				}
+				hasValue = true
+				this.push("." + name)
+				if o.Kind() == reflect.Map {
+					elem := reflect.New(o.Type().Elem()).Elem()
+					if err := this.decodeValue(elem); err != nil {
+						return err
+					}
+					o.SetMapIndex(reflect.ValueOf(name).Convert(o.Type().Key()), elem)
+				} else if f := cachedFields(o.Type()).byName(name); f != nil && f.asString {
+					if err := this.decodeStringOption(o.FieldByIndex(f.index)); err != nil {
+						return err
+					}
+				} else if f != nil {
+					if err := this.decodeValue(o.FieldByIndex(f.index)); err != nil {
+						return err
+					}
+				} else if err := this.d.Skip(); err != nil {
+					return err
+				}
+				this.pop()
+			default:
+				if err := this.unknownElement(v, memberTag); err != nil {
+					return err
+				}
 			}
 		case xml.EndElement:
 			switch v.Name.Local {
 			case string(memberTag):
+				if !hasValue && this.Mode == StrictMode {
+					return fmt.Errorf("xmlrpc: struct member %s without value", name)
+				}
 				return nil
 			case string(nameTag):
 				// ignore
@@ -542,60 +1043,233 @@ func (this *decoder) decodeMember(o reflect.Value) error {
 	return fmt.Errorf("this point shouldn't be reached")
 }
 
-// doesn't close the current element
-func (this *decoder) readNextCharData() (string, error) {
-	for {
-		t, err := this.d.Token()
-		if err != nil {
-			return "", err
-		}
-		switch v := t.(type) {
-		case xml.CharData:
-			return string(v), nil
-		case xml.EndElement:
-			return "", fmt.Errorf("expected xml.CharData but go xml.EndElement %s", v)
-		}
+// decodeStringOption decodes a value into a field tagged with the string option.
+// Besides the plain XML-RPC type of the field a <string> holding its text form is accepted.
+func (this *Decoder) decodeStringOption(o reflect.Value) error {
+	var n interface{}
+	if err := this.decodeValue(reflect.ValueOf(&n).Elem()); err != nil {
+		return err
 	}
-	return "", fmt.Errorf("this point shouldn't be reached")
+	switch n := n.(type) {
+	case string:
+		return parseScalar(o, n)
+	case int64:
+		return setInt(o, n)
+	case float64:
+		return setFloat(o, n)
+	case *big.Int:
+		return setIntText(o, n.String())
+	case *big.Float:
+		return setFloatText(o, n.Text('f', -1))
+	case bool:
+		return setBool(o, n)
+	}
+	return &TypeError{Value: typeName(n), Type: o.Type()}
+}
+
+// dropSpace is a strings.Map function removing white space.
+func dropSpace(r rune) rune {
+	if unicode.IsSpace(r) {
+		return -1
+	}
+	return r
+}
+
+// An Encoder writes XML-RPC documents to an output stream.
+// Several documents can be written to the same stream one after another,
+// an error only fails the document during which it occurred.
+type Encoder struct {
+	// Prefix and Indent enable pretty printing: each element begins on a
+	// new line starting with Prefix followed by one copy of Indent per
+	// nesting level. Scalar values are kept on the line of their type element.
+	Prefix string
+	Indent string
+
+	// Extensions enables the Apache XML-RPC extension types: nil is encoded as
+	// <ex:nil/>, int8, int16 and uint8 as <ex:i1>/<ex:i2>, 64 bit integers as
+	// <ex:i8> and float32 as <ex:float>. *big.Int is encoded as <ex:biginteger>
+	// instead of <string>, *big.Float and *big.Rat as <ex:bigdecimal> instead
+	// of <double>. Without it only the types of the specification and <nil/> are written.
+	Extensions bool
+
+	// TimeFormat selects how dateTime values are written, TimeSpec by default.
+	TimeFormat TimeFormat
+
+	// NilPolicy selects how nil pointers, maps, slices and interfaces are written, NilValue by default.
+	NilPolicy NilPolicy
+
+	// TextStrings encodes values implementing encoding.TextMarshaler or
+	// fmt.Stringer as <string>, except Marshalers and times, and allows
+	// fmt.Stringer map keys. Without it values without an XML-RPC
+	// representation are reported as *UnsupportedTypeError.
+	TextStrings bool
+
+	// UnsortedMaps writes the members of maps in iteration order instead of
+	// sorted by name, which saves sorting large maps but makes the output vary.
+	UnsortedMaps bool
+
+	w   io.Writer
+	err error // first error encountered while encoding the current document
+
+	depth      int
+	indentedIn bool
+	putNewline bool
+
+	visiting map[ref]bool          // pointers, maps and slices being written
+	emptying map[reflect.Type]bool // types whose empty value is being written
+	path     []string              // path of the value being written
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// EncodeCall writes a <methodCall> document for method with args as params.
+// It returns the first error encountered while encoding, including write errors.
+func (this *Encoder) EncodeCall(method string, args ...interface{}) error {
+	this.startDocument()
+	this.openRootTag(methodCallTag)
+	this.openTag(methodNameTag)
+	this.writeText(method)
+	this.closeTag(methodNameTag)
+	this.openTag(paramsTag)
+	for i, o := range args {
+		this.push(fmt.Sprintf("params[%d]", i))
+		this.writeParam(o)
+		this.pop()
+	}
+	this.closeTag(paramsTag)
+	this.closeTag(methodCallTag)
+	return this.endDocument()
+}
+
+// EncodeResponse writes a <methodResponse> document with v as its single param.
+func (this *Encoder) EncodeResponse(v interface{}) error {
+	this.startDocument()
+	this.openRootTag(methodResponseTag)
+	this.openTag(paramsTag)
+	this.push("params[0]")
+	this.writeParam(v)
+	this.pop()
+	this.closeTag(paramsTag)
+	this.closeTag(methodResponseTag)
+	return this.endDocument()
+}
+
+// EncodeFault writes a <methodResponse> document holding a fault
+// with the members faultCode and faultString.
+func (this *Encoder) EncodeFault(code int, message string) error {
+	this.startDocument()
+	this.openRootTag(methodResponseTag)
+	this.openTag(faultTag)
+	this.openTag(valueTag)
+	this.write(struct {
+		FaultCode   int    `xmlrpc:"faultCode"`
+		FaultString string `xmlrpc:"faultString"`
+	}{code, message})
+	this.closeTag(valueTag)
+	this.closeTag(faultTag)
+	this.closeTag(methodResponseTag)
+	return this.endDocument()
+}
+
+func (this *Encoder) writeParam(o interface{}) {
+	this.openTag(paramTag)
+	this.openTag(valueTag)
+	this.write(o)
+	this.closeTag(valueTag)
+	this.closeTag(paramTag)
 }
 
-type encoder struct {
-	w io.Writer
+func (this *Encoder) startDocument() {
+	this.depth, this.indentedIn, this.putNewline = 0, false, false
+	this.err, this.path = nil, this.path[:0]
+	this.writeRaw(xml.Header)
 }
 
-func (this *encoder) write(o interface{}) {
+func (this *Encoder) endDocument() error {
+	if this.putNewline {
+		this.writeRaw("\n")
+	}
+	return this.err
+}
+
+func (this *Encoder) write(o interface{}) {
 	if o == nil {
-		this.writeNil()
+		this.writeNilOf(nil)
+		return
+	}
+
+	// types implementing Marshaler encode themselves
+	if m, ok := o.(Marshaler); ok {
+		if f := reflect.ValueOf(o); f.Kind() == reflect.Ptr && f.IsNil() {
+			this.writeNilOf(f.Type())
+		} else if err := m.MarshalXMLRPC(this); err != nil && this.err == nil {
+			this.err = fmt.Errorf("xmlrpc: error calling MarshalXMLRPC for type %T: %w", o, err)
+		}
+		return
+	}
+
+	if this.writeBigNumber(o) {
+		return
+	}
+
+	if this.TextStrings {
+		if s, ok := this.textOf(o); ok {
+			this.writeString(s)
+			return
+		}
 	}
 
 	// use simple type switch if possible and use the refelction switch only as fallback
 	switch f := reflect.ValueOf(o); f.Kind() {
+	case reflect.Ptr:
+		if f.IsNil() {
+			this.writeNilOf(f.Type())
+		} else if this.enterRef(f) {
+			// interfaces pointed to are unwrapped by Interface
+			this.write(f.Elem().Interface())
+			this.leaveRef(f)
+		}
 	case reflect.Bool:
 		this.writeBoolean(f.Bool())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		this.writeInt(f.Int())
+		this.writeInt(f.Int(), f.Kind())
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		this.writeUint(f.Uint())
+		this.writeUint(f.Uint(), f.Kind())
 	case reflect.Float32, reflect.Float64:
-		this.writeFloat(f.Float())
+		this.writeFloat(f.Float(), f.Kind())
 	case reflect.String:
 		this.writeString(f.String())
 	case reflect.Array, reflect.Slice:
+		if f.Kind() == reflect.Slice {
+			if f.IsNil() {
+				this.writeNilOf(f.Type())
+				break
+			}
+			if !this.enterRef(f) {
+				break
+			}
+			defer this.leaveRef(f)
+		}
 		switch o.(type) {
 		case []byte:
 			// byte arrays are special
 			this.writeBytes(o.([]byte))
 		default:
-			openTag(this.w, arrayTag)
-			openTag(this.w, dataTag)
+			this.openTag(arrayTag)
+			this.openTag(dataTag)
 
 			for i := 0; i < f.Len(); i++ {
-				openTag(this.w, valueTag)
+				this.openTag(valueTag)
+				this.push(fmt.Sprintf("[%d]", i))
 				this.write(f.Index(i).Interface())
-				closeTag(this.w, valueTag)
+				this.pop()
+				this.closeTag(valueTag)
 			}
-			closeTag(this.w, dataTag)
-			closeTag(this.w, arrayTag)
+			this.closeTag(dataTag)
+			this.closeTag(arrayTag)
 		}
 	case reflect.Struct:
 		// time is special
@@ -604,108 +1278,230 @@ func (this *encoder) write(o interface{}) {
 			break
 		}
 
-		openTag(this.w, structTag)
-		for i := 0; i < f.NumField(); i++ {
-			field := f.Type().Field(i)
-			// check if field is exported
-			if field.PkgPath == "" {
-				openTag(this.w, memberTag)
-				openTag(this.w, nameTag)
-				xml.Escape(this.w, []byte(strings.ToLower(field.Name)))
-				closeTag(this.w, nameTag)
-				openTag(this.w, valueTag)
-				this.write(getField(f, i).Interface())
-				closeTag(this.w, valueTag)
-				closeTag(this.w, memberTag)
+		this.openTag(structTag)
+		for _, field := range cachedFields(f.Type()) {
+			fv := getField(f, field.index)
+			if field.omitEmpty && isEmptyValue(fv) || this.NilPolicy == NilOmit && isNilValue(fv) {
+				continue
+			}
+			this.openTag(memberTag)
+			this.openTag(nameTag)
+			this.writeText(field.name)
+			this.closeTag(nameTag)
+			this.openTag(valueTag)
+			this.push(memberPath(field.name))
+			if field.asString {
+				this.writeString(formatScalar(fv))
+			} else {
+				this.write(fv.Interface())
 			}
+			this.pop()
+			this.closeTag(valueTag)
+			this.closeTag(memberTag)
 		}
-		closeTag(this.w, structTag)
+		this.closeTag(structTag)
 	case reflect.Map:
-		if len(f.MapKeys()) == 0 || f.MapKeys()[0].Kind() != reflect.String {
+		if !this.isKeyType(f.Type().Key()) {
+			this.unsupported(f.Type())
 			break
 		}
-		for _, key := range f.MapKeys() {
-			openTag(this.w, memberTag)
-			openTag(this.w, nameTag)
-			xml.Escape(this.w, []byte(key.String()))
-			closeTag(this.w, nameTag)
-			openTag(this.w, valueTag)
-			this.write(f.MapIndex(key).Interface())
-			closeTag(this.w, valueTag)
-			closeTag(this.w, memberTag)
+		if f.IsNil() {
+			this.writeNilOf(f.Type())
+			break
+		}
+		if this.enterRef(f) {
+			this.writeMap(f)
+			this.leaveRef(f)
 		}
+	default:
+		// complex numbers, channels, funcs and unsafe pointers
+		this.unsupported(f.Type())
 	}
 }
-func (this *encoder) writeTime(time time.Time) {
-	openTag(this.w, dateTimeTag)
-	io.WriteString(this.w, time.Format(iso8601Format))
-	closeTag(this.w, dateTimeTag)
+
+// writeMap writes the map f as struct, its members sorted by name unless UnsortedMaps is set.
+func (this *Encoder) writeMap(f reflect.Value) {
+	type entry struct {
+		name  string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, f.Len())
+	for iter := f.MapRange(); iter.Next(); {
+		if this.NilPolicy == NilOmit && isNilValue(iter.Value()) {
+			continue
+		}
+		entries = append(entries, entry{this.keyName(iter.Key()), iter.Value()})
+	}
+	if !this.UnsortedMaps {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	}
+
+	this.openTag(structTag)
+	for _, e := range entries {
+		this.openTag(memberTag)
+		this.openTag(nameTag)
+		this.writeText(e.name)
+		this.closeTag(nameTag)
+		this.openTag(valueTag)
+		this.push(memberPath(e.name))
+		this.write(e.value.Interface())
+		this.pop()
+		this.closeTag(valueTag)
+		this.closeTag(memberTag)
+	}
+	this.closeTag(structTag)
 }
-func (this *encoder) writeBytes(b []byte) {
-	openTag(this.w, base64Tag)
-	io.WriteString(this.w, base64.StdEncoding.EncodeToString(b))
-	closeTag(this.w, base64Tag)
+
+func (this *Encoder) writeTime(time time.Time) {
+	this.openTag(dateTimeTag)
+	this.writeRaw(this.formatTime(time))
+	this.closeTag(dateTimeTag)
 }
-func (this *encoder) writeNil() {
-	openCloseTag(this.w, nilTag)
+func (this *Encoder) writeBytes(b []byte) {
+	this.openTag(base64Tag)
+	this.writeRaw(base64.StdEncoding.EncodeToString(b))
+	this.closeTag(base64Tag)
+}
+func (this *Encoder) writeNil() {
+	if this.Extensions {
+		this.openCloseTag(nilTag.ext())
+	} else {
+		this.openCloseTag(nilTag)
+	}
 }
 
-func (this *encoder) writeString(s string) {
-	openTag(this.w, stringTag)
-	xml.Escape(this.w, []byte(s))
-	closeTag(this.w, stringTag)
+func (this *Encoder) writeString(s string) {
+	this.openTag(stringTag)
+	this.writeText(s)
+	this.closeTag(stringTag)
 }
 
-func (this *encoder) writeFloat(f float64) {
-	openTag(this.w, doubleTag)
-	io.WriteString(this.w, strconv.FormatFloat(f, 'f', 10, 64))
-	closeTag(this.w, doubleTag)
+func (this *Encoder) writeFloat(f float64, k reflect.Kind) {
+	t := doubleTag
+	if this.Extensions && k == reflect.Float32 {
+		t = exFloatTag.ext()
+	}
+	this.openTag(t)
+	this.writeRaw(strconv.FormatFloat(f, 'f', 10, 64))
+	this.closeTag(t)
 }
-func (this *encoder) writeBoolean(b bool) {
-	openTag(this.w, booleanTag)
+func (this *Encoder) writeBoolean(b bool) {
+	this.openTag(booleanTag)
 	if b {
-		io.WriteString(this.w, "0")
+		this.writeRaw("1")
 	} else {
-		io.WriteString(this.w, "1")
+		this.writeRaw("0")
 	}
-	closeTag(this.w, booleanTag)
+	this.closeTag(booleanTag)
 }
-func (this *encoder) writeUint(i uint64) {
-	openTag(this.w, integerTag)
-	io.WriteString(this.w, strconv.FormatUint(i, 10))
-	closeTag(this.w, integerTag)
+func (this *Encoder) writeUint(i uint64, k reflect.Kind) {
+	t := this.uintTag(i, k)
+	this.openTag(t)
+	this.writeRaw(strconv.FormatUint(i, 10))
+	this.closeTag(t)
 }
-func (this *encoder) writeInt(i int64) {
-	openTag(this.w, integerTag)
-	io.WriteString(this.w, strconv.FormatInt(i, 10))
-	closeTag(this.w, integerTag)
+func (this *Encoder) writeInt(i int64, k reflect.Kind) {
+	t := this.intTag(i, k)
+	this.openTag(t)
+	this.writeRaw(strconv.FormatInt(i, 10))
+	this.closeTag(t)
 }
 
-// Get the i'th arg of the struct value.
-// If the arg itself is an interface, return a value for
+// Get the field of the struct value with the given index sequence.
+// If the field itself is an interface, return a value for
 // the thing inside the interface, not the interface itself.
 // (stolen from the fmt package)
-func getField(v reflect.Value, i int) reflect.Value {
-	val := v.Field(i)
+func getField(v reflect.Value, index []int) reflect.Value {
+	val := v.FieldByIndex(index)
 	if val.Kind() == reflect.Interface && !val.IsNil() {
 		val = val.Elem()
 	}
 	return val
 }
-func openTag(w io.Writer, t tag) {
-	io.WriteString(w, "<")
-	io.WriteString(w, string(t))
-	io.WriteString(w, ">")
+
+// formatScalar returns the text form of a number or boolean for the string tag option.
+func formatScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	}
+	return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+}
+
+func (this *Encoder) openTag(t tag) {
+	this.writeIndent(1)
+	this.writeRaw("<" + string(t) + ">")
+}
+
+// openRootTag opens the root element of a document, declaring
+// the extensions namespace if extension types are enabled.
+func (this *Encoder) openRootTag(t tag) {
+	this.writeIndent(1)
+	if this.Extensions {
+		this.writeRaw("<" + string(t) + ` xmlns:ex="` + extensionsNamespace + `">`)
+	} else {
+		this.writeRaw("<" + string(t) + ">")
+	}
+}
+
+func (this *Encoder) closeTag(t tag) {
+	this.writeIndent(-1)
+	this.writeRaw("</" + string(t) + ">")
 }
 
-func closeTag(w io.Writer, t tag) {
-	io.WriteString(w, "</")
-	io.WriteString(w, string(t))
-	io.WriteString(w, ">")
+func (this *Encoder) openCloseTag(t tag) {
+	this.writeIndent(0)
+	this.writeRaw("<" + string(t) + "/>")
 }
 
-func openCloseTag(w io.Writer, t tag) {
-	io.WriteString(w, "<")
-	io.WriteString(w, string(t))
-	io.WriteString(w, "/>")
+// writeIndent starts a new indented line for an element
+// (stolen from the encoding/xml package)
+func (this *Encoder) writeIndent(depthDelta int) {
+	if len(this.Prefix) == 0 && len(this.Indent) == 0 {
+		return
+	}
+	if depthDelta < 0 {
+		this.depth--
+		if this.indentedIn {
+			// keep the close tag of a scalar element on the same line
+			this.indentedIn = false
+			return
+		}
+		this.indentedIn = false
+	}
+	if this.putNewline {
+		this.writeRaw("\n")
+	} else {
+		this.putNewline = true
+	}
+	this.writeRaw(this.Prefix)
+	for i := 0; i < this.depth; i++ {
+		this.writeRaw(this.Indent)
+	}
+	if depthDelta > 0 {
+		this.depth++
+		this.indentedIn = true
+	}
+}
+
+// writeText writes s with XML special characters escaped.
+func (this *Encoder) writeText(s string) {
+	if this.err != nil {
+		return
+	}
+	this.err = xml.EscapeText(this.w, []byte(s))
+}
+
+// writeRaw writes s unless a previous write failed.
+func (this *Encoder) writeRaw(s string) {
+	if this.err != nil {
+		return
+	}
+	_, this.err = io.WriteString(this.w, s)
 }
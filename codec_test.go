@@ -2,6 +2,11 @@ package xmlrpc
 
 import (
 	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -122,7 +127,7 @@ func TestUnmarshalResp(t *testing.T) {
 			t.Errorf("expected %s got %s\n", "South Dakota", a[0])
 		}
 		if a[1].(int64) != 7 {
-			t.Errorf("expected %s got %s\n", 7, a[1])
+			t.Errorf("expected %d got %v\n", 7, a[1])
 		}
 		tim, _ := time.Parse(iso8601Format, "19980717T14:08:55")
 		if a[2].(time.Time) != tim {
@@ -134,7 +139,7 @@ func TestUnmarshalResp(t *testing.T) {
 		}
 		if arr, ok := a[5].([]interface{}); ok {
 			if len(arr) != 3 {
-				t.Errorf("expected len %s got %s\n", "3", len(arr))
+				t.Errorf("expected len %d got %d\n", 3, len(arr))
 			}
 		} else {
 			t.Errorf("expected %s got %T\n", "array", a[5])
@@ -145,3 +150,462 @@ func TestUnmarshalResp(t *testing.T) {
 	}
 
 }
+
+func TestUnmarshalResponseTyped(t *testing.T) {
+	s := `<?xml version="1.0"?>
+		<methodResponse>
+		  <params>
+		    <param>
+		      <value>
+		        <struct>
+		          <member>
+		            <name>title</name>
+		            <value><string>Hello</string></value>
+		          </member>
+		          <member>
+		            <name>postId</name>
+		            <value><i4>42</i4></value>
+		          </member>
+		          <member>
+		            <name>created</name>
+		            <value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value>
+		          </member>
+		          <member>
+		            <name>tags</name>
+		            <value><array><data>
+		              <value><string>go</string></value>
+		              <value><string>xml</string></value>
+		            </data></array></value>
+		          </member>
+		          <member>
+		            <name>ratings</name>
+		            <value><struct>
+		              <member><name>alice</name><value><double>4.5</double></value></member>
+		              <member><name>bob</name><value><double>3</double></value></member>
+		            </struct></value>
+		          </member>
+		          <member>
+		            <name>author</name>
+		            <value><struct>
+		              <member><name>name</name><value><string>Bob</string></value></member>
+		            </struct></value>
+		          </member>
+		          <member>
+		            <name>unknown</name>
+		            <value><struct><member><name>x</name><value><i4>1</i4></value></member></struct></value>
+		          </member>
+		        </struct>
+		      </value>
+		    </param>
+		    <param>
+		      <value><array><data>
+		        <value><i4>1</i4></value>
+		        <value><i4>2</i4></value>
+		      </data></array></value>
+		    </param>
+		    <param>
+		      <value><base64>eW91IGNhbid0IHJlYWQgdGhpcyE=</base64></value>
+		    </param>
+		  </params>
+		</methodResponse>`
+
+	type author struct {
+		Name string
+	}
+	var post struct {
+		Title   string
+		PostID  int
+		Created time.Time
+		Tags    []string
+		Ratings map[string]float64
+		Author  *author
+	}
+	var arr [3]int32
+	var data []byte
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &post, &arr, &data); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if post.Title != "Hello" || post.PostID != 42 {
+		t.Errorf("unexpected post %+v", post)
+	}
+	if tim, _ := time.Parse(iso8601Format, "19980717T14:08:55"); !post.Created.Equal(tim) {
+		t.Errorf("expected %v got %v", tim, post.Created)
+	}
+	if len(post.Tags) != 2 || post.Tags[1] != "xml" {
+		t.Errorf("unexpected tags %v", post.Tags)
+	}
+	if post.Ratings["alice"] != 4.5 || post.Ratings["bob"] != 3 {
+		t.Errorf("unexpected ratings %v", post.Ratings)
+	}
+	if post.Author == nil || post.Author.Name != "Bob" {
+		t.Errorf("unexpected author %+v", post.Author)
+	}
+	if arr != [3]int32{1, 2, 0} {
+		t.Errorf("unexpected array %v", arr)
+	}
+	if string(data) != "you can't read this!" {
+		t.Errorf("unexpected base64 %q", data)
+	}
+}
+
+func TestUnmarshalResponseTypeError(t *testing.T) {
+	s := `<methodResponse><params><param><value><string>seven</string></value></param></params></methodResponse>`
+	var i int
+	err := UnmarshalResponse(bytes.NewBufferString(s), &i)
+	if te, ok := err.(*TypeError); !ok {
+		t.Fatalf("expected *TypeError got %T %v", err, err)
+	} else if te.Value != "string" || te.Type.Kind() != reflect.Int {
+		t.Errorf("unexpected type error %v", te)
+	}
+}
+
+type taggedPost struct {
+	Title   string `xmlrpc:"post_title"`
+	Created string `xmlrpc:"dateCreated,omitempty"`
+	ID      int    `xmlrpc:"postId,string"`
+	Secret  string `xmlrpc:"-"`
+	Count   int
+}
+
+func TestMarshalStructTags(t *testing.T) {
+	buf := new(bytes.Buffer)
+	Marshal(buf, "test.method", taggedPost{Title: "hallo", ID: 7, Secret: "s", Count: 3})
+	s := buf.String()
+	for _, want := range []string{
+		"<member><name>post_title</name><value><string>hallo</string></value></member>",
+		"<member><name>postId</name><value><string>7</string></value></member>",
+		"<member><name>count</name><value><int>3</int></value></member>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %s in %s", want, s)
+		}
+	}
+	for _, unwanted := range []string{"dateCreated", "secret", "Secret"} {
+		if strings.Contains(s, unwanted) {
+			t.Errorf("unexpected %s in %s", unwanted, s)
+		}
+	}
+}
+
+func TestUnmarshalStructTags(t *testing.T) {
+	s := `<methodResponse><params><param><value><struct>
+		<member><name>post_title</name><value><string>hallo</string></value></member>
+		<member><name>dateCreated</name><value><string>today</string></value></member>
+		<member><name>postId</name><value><string>12</string></value></member>
+		<member><name>secret</name><value><string>s</string></value></member>
+		<member><name>Count</name><value><int>3</int></value></member>
+	</struct></value></param></params></methodResponse>`
+	var p taggedPost
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &p); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if p != (taggedPost{Title: "hallo", Created: "today", ID: 12, Count: 3}) {
+		t.Errorf("unexpected result %+v", p)
+	}
+}
+
+type failingWriter struct {
+	n int
+}
+
+func (this *failingWriter) Write(p []byte) (int, error) {
+	if this.n -= len(p); this.n < 0 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	e := NewEncoder(&failingWriter{n: 100})
+	if err := e.EncodeCall("test.method", "a long enough string to exceed the limit"); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected write error got %v", err)
+	}
+}
+
+func TestEncoderReuseAfterError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	if err := e.EncodeResponse(make(chan int)); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+	buf.Reset()
+	if err := e.EncodeResponse(1); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var i int
+	if err := UnmarshalResponse(buf, &i); err != nil || i != 1 {
+		t.Errorf("unexpected param %d err:%v", i, err)
+	}
+}
+
+func TestEncoderIndent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.Indent = "  "
+	if err := e.EncodeResponse([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+	expected := xml.Header + `<methodResponse>
+  <params>
+    <param>
+      <value>
+        <array>
+          <data>
+            <value>
+              <int>1</int>
+            </value>
+          </data>
+        </array>
+      </value>
+    </param>
+  </params>
+</methodResponse>
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestEncoderDecoderStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	if err := e.EncodeCall("test.add", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EncodeCall("test.name<>", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EncodeResponse(3.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EncodeFault(4, "Too many parameters."); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(buf)
+	method, err := d.DecodeCall()
+	if err != nil || method != "test.add" {
+		t.Fatalf("unexpected method %s err:%v", method, err)
+	}
+	var i int
+	var b bool
+	if err := d.DecodeParams(&i, &b); err != nil || i != 1 || !b {
+		t.Fatalf("unexpected params %d %t err:%v", i, b, err)
+	}
+	if method, err = d.DecodeCall(); err != nil || method != "test.name<>" {
+		t.Fatalf("unexpected method %s err:%v", method, err)
+	}
+	// skip params
+	if err := d.DecodeParams(); err != nil {
+		t.Fatal(err)
+	}
+	var f float64
+	if err := d.DecodeResponse(&f); err != nil || f != 3.5 {
+		t.Fatalf("unexpected response %f err:%v", f, err)
+	}
+	if err := d.DecodeResponse(&f); err == nil || !strings.Contains(err.Error(), "Too many parameters.") {
+		t.Fatalf("expected fault got %v", err)
+	}
+	if err := d.DecodeResponse(&f); err != io.EOF {
+		t.Fatalf("expected EOF got %v", err)
+	}
+}
+
+func TestUnmarshalCall(t *testing.T) {
+	s := `<?xml version="1.0"?>
+		<methodCall>
+		  <methodName>examples.getStateName</methodName>
+		  <params>
+		    <param>
+		        <value><i4>41</i4></value>
+		    </param>
+		    <param>
+		        <value><array><data><value><string>a</string></value></data></array></value>
+		    </param>
+		  </params>
+		</methodCall>`
+
+	var state int
+	var list []string
+	method, err := UnmarshalCall(bytes.NewBufferString(s), &state, &list)
+	if err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if method != "examples.getStateName" || state != 41 || len(list) != 1 || list[0] != "a" {
+		t.Errorf("unexpected call %s(%d, %v)", method, state, list)
+	}
+
+	var o interface{}
+	if err := Unmarshal(bytes.NewBufferString(s), &o); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	m := o.(map[string]interface{})
+	if m["methodName"] != "examples.getStateName" {
+		t.Errorf("expected %s got %v", "examples.getStateName", m["methodName"])
+	}
+	if params, ok := m["params"].([]interface{}); !ok || len(params) != 2 || params[0] != int64(41) {
+		t.Errorf("unexpected params %v", m["params"])
+	}
+}
+
+func TestMarshalResponseAndFault(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := MarshalResponse(buf, "South Dakota"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := UnmarshalResponse(buf, &name); err != nil || name != "South Dakota" {
+		t.Errorf("unexpected response %s err:%v", name, err)
+	}
+
+	buf.Reset()
+	if err := MarshalFault(buf, 4, "Too many parameters."); err != nil {
+		t.Fatal(err)
+	}
+	var o interface{}
+	if err := Unmarshal(buf, &o); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	fault, ok := o.(map[string]interface{})["fault"].(map[string]interface{})
+	if !ok || fault["faultCode"] != int64(4) || fault["faultString"] != "Too many parameters." {
+		t.Errorf("unexpected fault %v", o)
+	}
+}
+
+func TestUnmarshalResponseFault(t *testing.T) {
+	buf := new(bytes.Buffer)
+	MarshalFault(buf, 4, "Too many parameters.")
+	var s string
+	err := UnmarshalResponse(buf, &s)
+	fault, ok := err.(*Fault)
+	if !ok {
+		t.Fatalf("expected *Fault got %T %v", err, err)
+	}
+	if fault.Code != 4 || fault.String != "Too many parameters." {
+		t.Errorf("unexpected fault %+v", fault)
+	}
+}
+
+func TestUnmarshalUntypedAndWhitespace(t *testing.T) {
+	s := `<?xml version="1.0"?>
+		<methodResponse>
+		  <params>
+		    <param><value>South Dakota</value></param>
+		    <param><value>  padded  </value></param>
+		    <param><value></value></param>
+		    <param>
+		      <value>
+		        <int> 41 </int>
+		      </value>
+		    </param>
+		    <param><value><boolean>
+		      1
+		    </boolean></value></param>
+		    <param><value><double> 3.5<!-- comment -->0 </double></value></param>
+		    <param><value><string><![CDATA[<a> & <b>]]> text</string></value></param>
+		    <param><value><i4><![CDATA[1]]>2</i4></value></param>
+		    <param><value><base64>
+		      eW91IGNhbid0IHJlYWQg
+		      dGhpcyE=
+		    </base64></value></param>
+		    <param><value><string></string></value></param>
+		  </params>
+		</methodResponse>`
+
+	var (
+		untyped, padded, empty string
+		i, cdataInt            int
+		b                      bool
+		f                      float64
+		cdata                  string
+		data                   []byte
+		emptyString            interface{}
+	)
+	err := UnmarshalResponse(bytes.NewBufferString(s), &untyped, &padded, &empty, &i, &b, &f, &cdata, &cdataInt, &data, &emptyString)
+	if err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if untyped != "South Dakota" || padded != "  padded  " || empty != "" {
+		t.Errorf("unexpected untyped values %q %q %q", untyped, padded, empty)
+	}
+	if i != 41 || !b || f != 3.5 || cdataInt != 12 {
+		t.Errorf("unexpected scalar values %d %t %f %d", i, b, f, cdataInt)
+	}
+	if cdata != "<a> & <b> text" || string(data) != "you can't read this!" || emptyString != "" {
+		t.Errorf("unexpected values %q %q %#v", cdata, data, emptyString)
+	}
+
+	var o interface{}
+	s = `<methodResponse><params><param><value>Dakota</value></param></params></methodResponse>`
+	if err := Unmarshal(bytes.NewBufferString(s), &o); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if params := o.(map[string]interface{})["params"].([]interface{}); params[0] != "Dakota" {
+		t.Errorf("expected untyped string got %#v", params[0])
+	}
+
+	for _, s := range []string{
+		`<value>text<int>1</int></value>`,
+		`<value><int>1</int>text</value>`,
+		`<value><boolean>yes</boolean></value>`,
+	} {
+		s = "<methodResponse><params><param>" + s + "</param></params></methodResponse>"
+		if err := UnmarshalResponse(bytes.NewBufferString(s), &o); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+}
+
+func TestDecoderErrorPosition(t *testing.T) {
+	type entry struct {
+		Date time.Time `xmlrpc:"date"`
+	}
+	type blog struct {
+		Posts []entry `xmlrpc:"posts"`
+	}
+
+	s := `<methodResponse>
+<params>
+<param><value><string>ignored</string></value></param>
+<param><value><struct>
+<member><name>posts</name><value><array><data>
+<value><struct><member><name>date</name><value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value></member></struct></value>
+<value><struct><member><name>date</name><value><int>17</int></value></member></struct></value>
+</data></array></value></member>
+</struct></value></param>
+</params>
+</methodResponse>`
+	var b blog
+	err := UnmarshalResponse(strings.NewReader(s), new(string), &b)
+	typeErr, ok := err.(*TypeError)
+	if !ok {
+		t.Fatalf("expected *TypeError got %T %v", err, err)
+	}
+	if typeErr.Path != "params[1].posts[1].date" || typeErr.Line != 7 || typeErr.Column != 61 || typeErr.Offset == 0 {
+		t.Errorf("unexpected position %s line %d column %d offset %d", typeErr.Path, typeErr.Line, typeErr.Column, typeErr.Offset)
+	}
+	if expected := "xmlrpc: cannot unmarshal int into Go value of type time.Time at params[1].posts[1].date (line 7, column 61)"; err.Error() != expected {
+		t.Errorf("expected %s got %s", expected, err)
+	}
+
+	s = `<methodResponse>
+<params>
+<param><value><array><data><value><int>1</int></value><value><int>2</int></member></data></array></value></param>
+</params>
+</methodResponse>`
+	var o interface{}
+	err = UnmarshalResponse(strings.NewReader(s), &o)
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected *SyntaxError got %T %v", err, err)
+	}
+	if syntaxErr.Path != "params[0][1]" || syntaxErr.Line != 3 {
+		t.Errorf("unexpected position %s line %d", syntaxErr.Path, syntaxErr.Line)
+	}
+
+	s = `<methodResponse><params><param><value><struct><member><name>a</name><value><boolean>yes</boolean></value></member></struct></value></param></params></methodResponse>`
+	err = Unmarshal(strings.NewReader(s), &o)
+	if !errors.As(err, &syntaxErr) || syntaxErr.Path != "params[0].a" || syntaxErr.Line != 1 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
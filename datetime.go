@@ -0,0 +1,62 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A TimeFormat selects how an Encoder writes dateTime.iso8601 values.
+type TimeFormat int
+
+const (
+	// TimeSpec writes the bare form of the specification, 19980717T14:08:55,
+	// with the wall clock of the time's own location.
+	TimeSpec TimeFormat = iota
+	// TimeUTC converts times to UTC and writes them as 19980717T14:08:55Z.
+	TimeUTC
+	// TimeOffset writes times with their zone offset as 19980717T14:08:55+0200.
+	TimeOffset
+)
+
+// layouts of the dateTime.iso8601 values sent by common implementations,
+// fractional seconds are accepted by time.Parse after each of them
+var dateTimeLayouts = []string{
+	iso8601Format,
+	"20060102T15:04:05Z07:00",
+	"20060102T15:04:05Z0700",
+	"20060102T15:04:05Z07",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05Z07",
+	"20060102T150405",
+	"20060102T150405Z07:00",
+	"20060102T150405Z0700",
+}
+
+// parseDateTime parses the dateTime text s in any of the known layouts.
+// Values without a zone are interpreted in loc, or in UTC if loc is nil.
+func parseDateTime(s string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("xmlrpc: cannot parse %q as dateTime.iso8601", s)
+}
+
+// formatTime returns the text of t in the TimeFormat of the encoder.
+func (this *Encoder) formatTime(t time.Time) string {
+	switch this.TimeFormat {
+	case TimeUTC:
+		return t.UTC().Format(iso8601Format + "Z")
+	case TimeOffset:
+		return t.Format(iso8601Format + "-0700")
+	}
+	return t.Format(iso8601Format)
+}
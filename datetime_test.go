@@ -0,0 +1,80 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	for _, tt := range []struct {
+		s        string
+		expected time.Time
+	}{
+		{"19980717T14:08:55", time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC)},
+		{"19980717T14:08:05", time.Date(1998, 7, 17, 14, 8, 5, 0, time.UTC)},
+		{"1998-07-17T14:08:55Z", time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC)},
+		{"19980717T14:08:55+0200", time.Date(1998, 7, 17, 12, 8, 55, 0, time.UTC)},
+		{"19980717T14:08:55+02:00", time.Date(1998, 7, 17, 12, 8, 55, 0, time.UTC)},
+		{"1998-07-17T14:08:55.250-05:00", time.Date(1998, 7, 17, 19, 8, 55, 250000000, time.UTC)},
+		{"19980717T14:08:55.5", time.Date(1998, 7, 17, 14, 8, 55, 500000000, time.UTC)},
+		{"19980717T140855", time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC)},
+		{"\n  19980717T14:08:55 \n", time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC)},
+	} {
+		if tim, err := parseDateTime(tt.s, nil); err != nil || !tim.Equal(tt.expected) {
+			t.Errorf("%q: expected %v got %v err:%v", tt.s, tt.expected, tim, err)
+		}
+	}
+	if _, err := parseDateTime("17.07.1998 14:08", nil); err == nil {
+		t.Errorf("expected error for invalid date")
+	}
+
+	// Location only applies to values without zone
+	s := `<methodResponse><params>
+		<param><value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value></param>
+		<param><value><dateTime.iso8601>19980717T14:08:55Z</dateTime.iso8601></value></param>
+		</params></methodResponse>`
+	d := NewDecoder(strings.NewReader(s))
+	d.Location = cet
+	var local, utc time.Time
+	if err := d.DecodeResponse(&local, &utc); err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(1998, 7, 17, 14, 8, 55, 0, cet); !local.Equal(expected) || local.Location() != cet {
+		t.Errorf("expected %v got %v", expected, local)
+	}
+	if expected := time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC); !utc.Equal(expected) {
+		t.Errorf("expected %v got %v", expected, utc)
+	}
+}
+
+func TestEncodeTimeFormat(t *testing.T) {
+	tim := time.Date(1998, 7, 17, 14, 8, 5, 0, time.FixedZone("CEST", 7200))
+	for _, tt := range []struct {
+		format   TimeFormat
+		expected string
+	}{
+		{TimeSpec, "19980717T14:08:05"},
+		{TimeUTC, "19980717T12:08:05Z"},
+		{TimeOffset, "19980717T14:08:05+0200"},
+	} {
+		buf := new(bytes.Buffer)
+		e := NewEncoder(buf)
+		e.TimeFormat = tt.format
+		if err := e.EncodeResponse(tim); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "<dateTime.iso8601>" + tt.expected + "</dateTime.iso8601>"; !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in %s", expected, buf.String())
+		}
+		var decoded time.Time
+		if err := UnmarshalResponse(buf, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if tt.format != TimeSpec && !decoded.Equal(tim) {
+			t.Errorf("expected %v got %v", tim, decoded)
+		}
+	}
+}
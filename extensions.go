@@ -0,0 +1,97 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+// extensionsNamespace is the namespace of the Apache XML-RPC extension types.
+const extensionsNamespace = "http://ws.apache.org/xmlrpc/namespaces/extensions"
+
+// local names of the extension types, written with the ex prefix
+const (
+	exI1Tag         tag = "i1"
+	exI2Tag         tag = "i2"
+	exFloatTag      tag = "float"
+	exDateTimeTag   tag = "dateTime"
+	exBigIntegerTag tag = "biginteger"
+	exBigDecimalTag tag = "bigdecimal"
+)
+
+// ext returns the prefixed name of the extension type t.
+func (t tag) ext() tag {
+	return "ex:" + t
+}
+
+// isExtension reports whether name is in the extensions namespace. Documents
+// using the ex prefix without declaring it are accepted as well.
+func isExtension(name xml.Name) bool {
+	return name.Space == extensionsNamespace || name.Space == "ex"
+}
+
+func isIntegerTag(local string) bool {
+	switch tag(local) {
+	case integerTag, integerTag2, i8Tag, exI1Tag, exI2Tag, exBigIntegerTag:
+		return true
+	}
+	return false
+}
+
+// decodeExtension decodes the content of the extension type element local.
+func (this *Decoder) decodeExtension(o reflect.Value, local string) error {
+	switch tag(local) {
+	case i8Tag, exI1Tag, exI2Tag:
+		return this.decodeInt(o)
+	case exBigIntegerTag:
+		// big numbers keep their type in empty interfaces
+		if v := indirect(o); isEmptyInterface(v) {
+			v.Set(reflect.ValueOf(new(big.Int)))
+		}
+		return this.decodeInt(o)
+	case exFloatTag:
+		return this.decodeDouble(o)
+	case exBigDecimalTag:
+		if v := indirect(o); isEmptyInterface(v) {
+			v.Set(reflect.ValueOf(new(big.Float)))
+		}
+		return this.decodeDouble(o)
+	case nilTag:
+		return this.decodeNil(o)
+	case exDateTimeTag:
+		return this.decodeDate(o)
+	}
+	return fmt.Errorf("xmlrpc: unsupported extension type ex:%s", local)
+}
+
+// intTag returns the type element for the integer i of kind k.
+func (this *Encoder) intTag(i int64, k reflect.Kind) tag {
+	if !this.Extensions {
+		return integerTag
+	}
+	switch {
+	case k == reflect.Int8:
+		return exI1Tag.ext()
+	case k == reflect.Int16:
+		return exI2Tag.ext()
+	case k == reflect.Int64, i < math.MinInt32 || i > math.MaxInt32:
+		return i8Tag.ext()
+	}
+	return integerTag
+}
+
+// uintTag returns the type element for the unsigned integer i of kind k.
+func (this *Encoder) uintTag(i uint64, k reflect.Kind) tag {
+	if !this.Extensions {
+		return integerTag
+	}
+	switch {
+	case k == reflect.Uint8:
+		return exI2Tag.ext()
+	case k == reflect.Uint64, k == reflect.Uint32, k == reflect.Uintptr, i > math.MaxInt32:
+		return i8Tag.ext()
+	}
+	return integerTag
+}
@@ -0,0 +1,96 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalExtensions(t *testing.T) {
+	s := `<?xml version="1.0"?>
+		<methodResponse xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions">
+		  <params>
+		    <param><value><ex:i8>9223372036854775807</ex:i8></value></param>
+		    <param><value><i8>-5</i8></value></param>
+		    <param><value><ex:i1>-128</ex:i1></value></param>
+		    <param><value><ex:i2>1024</ex:i2></value></param>
+		    <param><value><ex:float>1.5</ex:float></value></param>
+		    <param><value><ex:nil/></value></param>
+		    <param><value><ex:dateTime>2008-07-02T13:35:56.123+0200</ex:dateTime></value></param>
+		    <param><value><ex:biginteger>42</ex:biginteger></value></param>
+		    <param><value><ex:bigdecimal>0.25</ex:bigdecimal></value></param>
+		  </params>
+		</methodResponse>`
+
+	var (
+		i8, neg int64
+		i1      int8
+		i2      int16
+		f       float32
+		n       = new(int)
+		date    time.Time
+		bigI    int
+		bigD    float64
+		unused  interface{}
+	)
+	err := UnmarshalResponse(bytes.NewBufferString(s), &i8, &neg, &i1, &i2, &f, &n, &date, &bigI, &bigD)
+	if err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if i8 != 9223372036854775807 || neg != -5 || i1 != -128 || i2 != 1024 || f != 1.5 || n != nil || bigI != 42 || bigD != 0.25 {
+		t.Errorf("unexpected values %d %d %d %d %f %v %d %f", i8, neg, i1, i2, f, n, bigI, bigD)
+	}
+	if expected := time.Date(2008, 7, 2, 11, 35, 56, 123000000, time.UTC); !date.Equal(expected) {
+		t.Errorf("expected %v got %v", expected, date)
+	}
+
+	// undeclared prefix
+	s = `<methodResponse><params><param><value><ex:i8>7</ex:i8></value></param></params></methodResponse>`
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &i8); err != nil || i8 != 7 {
+		t.Errorf("unexpected value %d err:%v", i8, err)
+	}
+
+	s = `<methodResponse><params><param><value><ex:serializable>rO0=</ex:serializable></value></param></params></methodResponse>`
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &unused); err == nil {
+		t.Errorf("expected error for unsupported extension type")
+	}
+}
+
+func TestMarshalExtensions(t *testing.T) {
+	args := []interface{}{int8(1), int16(2), int32(3), int64(4), 5, 1 << 40, uint8(6), uint32(7), float32(1.5), 2.5, nil}
+
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test.method", args...); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); strings.Contains(s, "ex:") || strings.Contains(s, "xmlns") {
+		t.Errorf("unexpected extension types in %s", s)
+	}
+
+	buf.Reset()
+	e := NewEncoder(buf)
+	e.Extensions = true
+	if err := e.EncodeCall("test.method", args...); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		`<methodCall xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions">`,
+		"<ex:i1>1</ex:i1>", "<ex:i2>2</ex:i2>", "<int>3</int>", "<ex:i8>4</ex:i8>", "<int>5</int>",
+		"<ex:i8>1099511627776</ex:i8>", "<ex:i2>6</ex:i2>", "<ex:i8>7</ex:i8>",
+		"<ex:float>1.5", "<double>2.5", "<ex:nil/>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %s in %s", want, s)
+		}
+	}
+
+	var o interface{}
+	if _, err := UnmarshalCall(bytes.NewBufferString(s), &o); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if o != int64(1) {
+		t.Errorf("expected %d got %v", 1, o)
+	}
+}
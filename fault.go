@@ -0,0 +1,64 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Fault codes used by the Server, following the specification
+// for fault code interoperability.
+const (
+	FaultParseError       = -32700
+	FaultInvalidRequest   = -32600
+	FaultMethodNotFound   = -32601
+	FaultInvalidParams    = -32602
+	FaultInternalError    = -32603
+	FaultApplicationError = -32500
+)
+
+// A Fault is an XML-RPC fault with its faultCode and faultString.
+// Client calls answered with a fault return a *Fault as error, handlers
+// registered with a Server can return one to answer with a specific code.
+type Fault struct {
+	Code   int    `xmlrpc:"faultCode"`
+	String string `xmlrpc:"faultString"`
+
+	// Members holds all members of a decoded fault struct,
+	// including those added by servers beyond faultCode and faultString.
+	Members map[string]interface{} `xmlrpc:"-"`
+}
+
+func (this *Fault) Error() string {
+	return fmt.Sprintf("xmlrpc: fault %d: %s", this.Code, this.String)
+}
+
+// UnmarshalXMLRPC decodes a fault struct, tolerating faultCode values sent as string.
+func (this *Fault) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	var v interface{}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*this = *newFault(v)
+	return nil
+}
+
+// newFault returns the fault for a generically decoded fault value.
+func newFault(v interface{}) *Fault {
+	f := &Fault{}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		f.String = fmt.Sprint(v)
+		return f
+	}
+	f.Members = m
+	switch code := m["faultCode"].(type) {
+	case int64:
+		f.Code = int(code)
+	case string:
+		fmt.Sscan(code, &f.Code)
+	}
+	if s, ok := m["faultString"].(string); ok {
+		f.String = s
+	}
+	return f
+}
@@ -0,0 +1,158 @@
+package xmlrpc
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// field describes an exported struct field mapped to a struct member.
+//
+// The member name defaults to the lower cased field name and can be
+// changed with a struct tag of the form
+//
+//	Field int `xmlrpc:"name,omitempty,string"`
+//
+// A tag of "-" skips the field, omitempty drops zero values when encoding
+// and string encodes numbers and booleans as <string> values.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	asString  bool
+}
+
+type fields []field
+
+// byName returns the field for the member name, preferring an exact
+// match over a case-insensitive one, or nil if there is none.
+func (this fields) byName(name string) *field {
+	var fold *field
+	for i := range this {
+		if this[i].name == name {
+			return &this[i]
+		}
+		if fold == nil && strings.EqualFold(this[i].name, name) {
+			fold = &this[i]
+		}
+	}
+	return fold
+}
+
+var fieldCache sync.Map // map[reflect.Type]fields
+
+// cachedFields returns the member fields of the struct type t.
+func cachedFields(t reflect.Type) fields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(fields)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return f.(fields)
+}
+
+// typeFields collects the member fields of t. Fields of embedded structs
+// without a tag are promoted unless a field of the outer struct has the same name.
+func typeFields(t reflect.Type, index []int) fields {
+	var fs, embedded fields
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("xmlrpc")
+		if tag == "-" {
+			continue
+		}
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			embedded = append(embedded, typeFields(sf.Type, idx)...)
+			continue
+		}
+		// skip unexported fields
+		if sf.PkgPath != "" {
+			continue
+		}
+		f := field{name: strings.ToLower(sf.Name), index: idx}
+		name, opts := parseTag(tag)
+		if name != "" {
+			f.name = name
+		}
+		f.omitEmpty = opts.contains("omitempty")
+		f.asString = opts.contains("string") && isStringable(sf.Type)
+		fs = append(fs, f)
+	}
+	for _, f := range embedded {
+		if !fs.contains(f.name) {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+func (this fields) contains(name string) bool {
+	for _, f := range this {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isStringable reports whether the string tag option applies to values of type t.
+func isStringable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+func (this tagOptions) contains(name string) bool {
+	s := string(this)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	listMethodsMethod     = "system.listMethods"
+	methodSignatureMethod = "system.methodSignature"
+	methodHelpMethod      = "system.methodHelp"
+)
+
+// builtin returns the introspection method name or nil.
+func (this *Server) builtin(name string) *method {
+	var (
+		fn   interface{}
+		help string
+	)
+	switch name {
+	case listMethodsMethod:
+		fn, help = this.listMethods, "Returns the names of all methods of the server."
+	case methodSignatureMethod:
+		fn, help = this.methodSignature, "Returns the signatures of a method as arrays of the result type followed by the param types."
+	case methodHelpMethod:
+		fn, help = this.methodHelp, "Returns the description of a method."
+	default:
+		return nil
+	}
+	m, _ := newMethod(reflect.ValueOf(fn))
+	m.help = help
+	return m
+}
+
+func (this *Server) listMethods() []string {
+	this.mu.RLock()
+	names := make([]string, 0, len(this.methods)+4)
+	for name := range this.methods {
+		names = append(names, name)
+	}
+	for _, name := range []string{listMethodsMethod, methodSignatureMethod, methodHelpMethod, multicallMethod} {
+		if _, ok := this.methods[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	this.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+func (this *Server) methodSignature(name string) ([][]string, error) {
+	if name == multicallMethod && this.lookup(name) == nil {
+		return [][]string{{"array", "array"}}, nil
+	}
+	m := this.lookup(name)
+	if m == nil {
+		return nil, &Fault{Code: FaultMethodNotFound, String: fmt.Sprintf("method %s not found", name)}
+	}
+	sig := make([]string, 0, len(m.args)+1)
+	if m.hasResult {
+		sig = append(sig, signatureType(m.fn.Type().Out(0)))
+	} else {
+		sig = append(sig, string(nilTag))
+	}
+	for _, t := range m.args {
+		sig = append(sig, signatureType(t))
+	}
+	return [][]string{sig}, nil
+}
+
+func (this *Server) methodHelp(name string) (string, error) {
+	if name == multicallMethod && this.lookup(name) == nil {
+		return "Calls each method of an array of structs with methodName and params and returns an array of the results.", nil
+	}
+	m := this.lookup(name)
+	if m == nil {
+		return "", &Fault{Code: FaultMethodNotFound, String: fmt.Sprintf("method %s not found", name)}
+	}
+	return m.help, nil
+}
+
+// signatureType returns the XML-RPC type encoding values of type t.
+func signatureType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return "undef"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return string(booleanTag)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return string(integerTag)
+	case reflect.Float32, reflect.Float64:
+		return string(doubleTag)
+	case reflect.String:
+		return string(stringTag)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return string(base64Tag)
+		}
+		return string(arrayTag)
+	case reflect.Struct:
+		if t == timeType {
+			return string(dateTimeTag)
+		}
+		return string(structTag)
+	case reflect.Map:
+		return string(structTag)
+	}
+	return "undef"
+}
+
+// ListMethods returns the method names reported by system.listMethods of the server of c.
+func ListMethods(c Client) ([]string, error) {
+	var names []string
+	err := c.CallInto(&names, listMethodsMethod)
+	return names, err
+}
+
+// MethodSignature returns the signatures of method reported by system.methodSignature,
+// each holding the result type followed by the param types. It returns no signatures
+// for servers answering a non-array value like "undef" for unknown signatures.
+func MethodSignature(c Client, method string) ([][]string, error) {
+	var v interface{}
+	if err := c.CallInto(&v, methodSignatureMethod, method); err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	sigs := make([][]string, 0, len(list))
+	for _, s := range list {
+		types, ok := s.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("xmlrpc: unexpected signature %v of %s", s, method)
+		}
+		sig := make([]string, len(types))
+		for i, t := range types {
+			if sig[i], ok = t.(string); !ok {
+				return nil, fmt.Errorf("xmlrpc: unexpected signature %v of %s", s, method)
+			}
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// MethodHelp returns the description of method reported by system.methodHelp.
+func MethodHelp(c Client, method string) (string, error) {
+	var help string
+	err := c.CallInto(&help, methodHelpMethod, method)
+	return help, err
+}
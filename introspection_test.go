@@ -0,0 +1,95 @@
+package xmlrpc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntrospection(t *testing.T) {
+	srv := NewServer()
+	srv.Register("blog", &blogService{})
+	srv.RegisterFunc("math.add", func(a, b int) int { return a + b }, WithHelp("Adds two integers."))
+	srv.RegisterFunc("misc.stamp", func(t time.Time, data []byte, tags []string, opts map[string]bool) (*post, error) { return nil, nil })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	names, err := ListMethods(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"blog.countPosts", "blog.fail", "blog.getPost", "math.add", "misc.stamp",
+		"system.listMethods", "system.methodHelp", "system.methodSignature", "system.multicall",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v got %v", expected, names)
+	}
+
+	for method, sig := range map[string][]string{
+		"math.add":           {"int", "int", "int"},
+		"blog.countPosts":    {"int"},
+		"blog.fail":          {"nil"},
+		"misc.stamp":         {"struct", "dateTime.iso8601", "base64", "array", "struct"},
+		"system.methodHelp":  {"string", "string"},
+		"system.listMethods": {"array"},
+	} {
+		sigs, err := MethodSignature(c, method)
+		if err != nil || len(sigs) != 1 || !reflect.DeepEqual(sigs[0], sig) {
+			t.Errorf("%s: expected %v got %v err:%v", method, sig, sigs, err)
+		}
+	}
+
+	if help, err := MethodHelp(c, "math.add"); err != nil || help != "Adds two integers." {
+		t.Errorf("unexpected help %q err:%v", help, err)
+	}
+	if help, err := MethodHelp(c, "system.listMethods"); err != nil || help == "" {
+		t.Errorf("unexpected help %q err:%v", help, err)
+	}
+	var fault *Fault
+	if _, err := MethodHelp(c, "math.sub"); !errors.As(err, &fault) || fault.Code != FaultMethodNotFound {
+		t.Errorf("expected fault %d got %v", FaultMethodNotFound, err)
+	}
+}
+
+func TestMethodSignatureUndef(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("system.methodSignature", func(name string) string { return "undef" })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	if sigs, err := MethodSignature(c, "any"); err != nil || sigs != nil {
+		t.Errorf("expected no signatures got %v err:%v", sigs, err)
+	}
+}
+
+type helpedService struct{}
+
+func (this helpedService) Ping() string {
+	return "pong"
+}
+
+func (this helpedService) MethodHelp(method string) string {
+	if method == "Ping" {
+		return "Answers pong."
+	}
+	return ""
+}
+
+func TestRegisterMethodHelper(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Register("svc", helpedService{}); err != nil {
+		t.Fatal(err)
+	}
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	if help, err := MethodHelp(c, "svc.ping"); err != nil || help != "Answers pong." {
+		t.Errorf("unexpected help %q err:%v", help, err)
+	}
+	var fault *Fault
+	if _, err := MethodHelp(c, "svc.methodHelp"); !errors.As(err, &fault) || fault.Code != FaultMethodNotFound {
+		t.Errorf("expected fault %d got %v", FaultMethodNotFound, err)
+	}
+}
@@ -0,0 +1,127 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLimitExceeded is wrapped by the errors returned when a document exceeds the Limits of a Decoder.
+var ErrLimitExceeded = errors.New("xmlrpc: decoding limit exceeded")
+
+// Limits restrict the resources spent on decoding a document from an untrusted peer.
+// A zero field means no limit.
+type Limits struct {
+	// MaxBytes limits the number of bytes read for each document. It is the only
+	// limit bounding memory use, as each text token is read in full before
+	// its length can be checked.
+	MaxBytes int64
+	// MaxDepth limits the nesting of values in arrays and structs.
+	MaxDepth int
+	// MaxMembers limits the number of params, array elements and struct members.
+	MaxMembers int
+	// MaxTextLength limits the number of bytes in the text of a value, e.g. a string or base64 value.
+	MaxTextLength int
+}
+
+// DefaultLimits are the limits of a Server without Limits.
+var DefaultLimits = Limits{
+	MaxBytes:      10 << 20,
+	MaxDepth:      64,
+	MaxMembers:    100000,
+	MaxTextLength: 10 << 20,
+}
+
+func limitError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrLimitExceeded}, args...)...)
+}
+
+// limitReader fails once more than MaxBytes of the decoder's limits are read for the current document.
+// As an io.ByteReader it is read by the xml.Decoder byte by byte without
+// buffering, so the bytes of a RawValue can be recorded while it is decoded.
+type limitReader struct {
+	r *bufio.Reader
+	d *Decoder
+	n int64
+
+	recording bool
+	record    []byte
+	// converted is set once the input is read through Read, which only
+	// happens for documents converted from another charset by CharsetReader
+	converted bool
+}
+
+func (this *limitReader) ReadByte() (byte, error) {
+	if max := this.d.Limits.MaxBytes; max > 0 && this.n >= max {
+		return 0, limitError("document larger than %d bytes", max)
+	}
+	b, err := this.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			err = &readError{err}
+		}
+		return 0, err
+	}
+	this.n++
+	if this.recording {
+		this.record = append(this.record, b)
+	}
+	return b, nil
+}
+
+func (this *limitReader) Read(p []byte) (int, error) {
+	this.converted = true
+	if max := this.d.Limits.MaxBytes; max > 0 {
+		if this.n >= max {
+			return 0, limitError("document larger than %d bytes", max)
+		}
+		if int64(len(p)) > max-this.n {
+			p = p[:max-this.n]
+		}
+	}
+	n, err := this.r.Read(p)
+	this.n += int64(n)
+	if err != nil && err != io.EOF {
+		err = &readError{err}
+	}
+	return n, err
+}
+
+// startDocument starts counting the bytes of the next document for MaxBytes.
+func (this *Decoder) startDocument() {
+	if this.in != nil {
+		this.in.n = 0
+	}
+}
+
+// enter is called when decoding of a value starts and fails if MaxDepth is exceeded.
+// Each call has to be followed by a call of leave.
+func (this *Decoder) enter() error {
+	this.depth++
+	if max := this.Limits.MaxDepth; max > 0 && this.depth > max {
+		return limitError("values nested deeper than %d", max)
+	}
+	return nil
+}
+
+func (this *Decoder) leave() {
+	this.depth--
+}
+
+// checkMembers fails if n params, elements or members exceed MaxMembers.
+func (this *Decoder) checkMembers(n int) error {
+	if max := this.Limits.MaxMembers; max > 0 && n > max {
+		return limitError("more than %d members", max)
+	}
+	return nil
+}
+
+// appendText appends the text token v to text and fails if the result exceeds MaxTextLength.
+func (this *Decoder) appendText(text []byte, v xml.CharData) ([]byte, error) {
+	if max := this.Limits.MaxTextLength; max > 0 && len(text)+len(v) > max {
+		return text, limitError("text longer than %d bytes", max)
+	}
+	return append(text, v...), nil
+}
@@ -0,0 +1,107 @@
+package xmlrpc
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func nestedArrays(depth int) string {
+	return strings.Repeat("<value><array><data>", depth) + "<value><int>1</int></value>" + strings.Repeat("</data></array></value>", depth)
+}
+
+func TestDecoderLimits(t *testing.T) {
+	response := func(params ...string) string {
+		return "<methodResponse><params><param>" + strings.Join(params, "</param><param>") + "</param></params></methodResponse>"
+	}
+	for _, tt := range []struct {
+		name   string
+		limits Limits
+		doc    string
+	}{
+		{"bytes", Limits{MaxBytes: 100}, response("<value><string>" + strings.Repeat("x", 200) + "</string></value>")},
+		{"depth", Limits{MaxDepth: 10}, response(nestedArrays(10))},
+		{"array", Limits{MaxMembers: 2}, response("<value><array><data>" + strings.Repeat("<value><int>1</int></value>", 3) + "</data></array></value>")},
+		{"struct", Limits{MaxMembers: 2}, response("<value><struct>" + strings.Repeat("<member><name>a</name><value>1</value></member>", 3) + "</struct></value>")},
+		{"params", Limits{MaxMembers: 2}, response("<value>1</value>", "<value>2</value>", "<value>3</value>")},
+		{"string", Limits{MaxTextLength: 10}, response("<value><string>" + strings.Repeat("x", 11) + "</string></value>")},
+		{"untyped", Limits{MaxTextLength: 10}, response("<value>" + strings.Repeat("x", 11) + "</value>")},
+		{"base64", Limits{MaxTextLength: 10}, response("<value><base64>eW91IGNhbid0IHJlYWQg</base64></value>")},
+		{"name", Limits{MaxTextLength: 10}, response("<value><struct><member><name>" + strings.Repeat("x", 50) + "</name><value>1</value></member></struct></value>")},
+	} {
+		d := NewDecoder(strings.NewReader(tt.doc))
+		d.Limits = tt.limits
+		var o interface{}
+		if err := d.DecodeResponse(&o); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: expected ErrLimitExceeded got %v", tt.name, err)
+		}
+
+		// the same document is accepted without limits
+		if err := UnmarshalResponse(strings.NewReader(tt.doc), &o); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+
+	d := NewDecoder(strings.NewReader(response(nestedArrays(9))))
+	d.Limits = Limits{MaxDepth: 10}
+	var o interface{}
+	if err := d.DecodeResponse(&o); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	call := "<methodCall><methodName>" + strings.Repeat("x", 50) + "</methodName><params></params></methodCall>"
+	d = NewDecoder(strings.NewReader(call))
+	d.Limits = Limits{MaxTextLength: 10}
+	if _, err := d.DecodeCall(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("methodName: expected ErrLimitExceeded got %v", err)
+	}
+	d = NewDecoder(strings.NewReader(call))
+	d.Limits = Limits{MaxTextLength: 10}
+	if err := d.read(&o); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("generic methodName: expected ErrLimitExceeded got %v", err)
+	}
+}
+
+func TestDecoderLimitsPerDocument(t *testing.T) {
+	doc := "<methodResponse><params><param><value><int>1</int></value></param></params></methodResponse>\n"
+	d := NewDecoder(strings.NewReader(strings.Repeat(doc, 5)))
+	d.Limits = Limits{MaxBytes: int64(len(doc)) + 10}
+	for i := 0; i < 5; i++ {
+		var n int
+		if err := d.DecodeResponse(&n); err != nil || n != 1 {
+			t.Fatalf("document %d: unexpected param %d err:%v", i, n, err)
+		}
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("echo", func(v interface{}) interface{} { return v })
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var fault *Fault
+	var result interface{}
+	var deep interface{} = 1
+	for i := 0; i < DefaultLimits.MaxDepth; i++ {
+		deep = []interface{}{deep}
+	}
+	if err := serverCall(t, ts.URL, "echo", &result, deep); !errors.As(err, &fault) || fault.Code != FaultInvalidRequest {
+		t.Errorf("expected fault %d got %v", FaultInvalidRequest, err)
+	}
+
+	srv.Limits = &Limits{MaxBytes: 200}
+	if err := serverCall(t, ts.URL, "echo", &result, strings.Repeat("x", 200)); !errors.As(err, &fault) || fault.Code != FaultInvalidRequest {
+		t.Errorf("expected fault %d got %v", FaultInvalidRequest, err)
+	}
+	if err := serverCall(t, ts.URL, "echo", &result, "x"); err != nil || result != "x" {
+		t.Errorf("unexpected result %v err:%v", result, err)
+	}
+
+	c, done := newTestClient(t, srv, WithLimits(Limits{MaxTextLength: 5}))
+	defer done()
+	if _, err := c.Call("echo", "too long"); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded got %v", err)
+	}
+}
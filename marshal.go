@@ -0,0 +1,112 @@
+package xmlrpc
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// An UnsupportedTypeError is returned by the Encoder for values without an
+// XML-RPC representation, e.g. channels, funcs, complex numbers and maps
+// with keys that cannot be written as member names.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+	Path string // path of the value, e.g. params[0].posts[17].date
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "xmlrpc: unsupported type " + e.Type.String() + position(e.Path, 0, 0)
+}
+
+// push appends the segment s, e.g. "params[0]", "[17]" or ".date", to the
+// path of the value being written. Each call has to be followed by a call of pop.
+func (this *Encoder) push(s string) {
+	this.path = append(this.path, s)
+}
+
+func (this *Encoder) pop() {
+	this.path = this.path[:len(this.path)-1]
+}
+
+// memberPath returns the path segment of the member name, e.g. ".date", or
+// ["a.b"] for names that are no identifiers and would make the path ambiguous.
+func memberPath(name string) string {
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return "[" + strconv.Quote(name) + "]"
+		}
+	}
+	if name == "" {
+		return `[""]`
+	}
+	return "." + name
+}
+
+// unsupported records an UnsupportedTypeError for t at the current path.
+func (this *Encoder) unsupported(t reflect.Type) {
+	if this.err == nil {
+		this.err = &UnsupportedTypeError{Type: t, Path: strings.Join(this.path, "")}
+	}
+}
+
+// textOf returns the text of o for TextStrings if o implements
+// encoding.TextMarshaler or fmt.Stringer. Times and nil pointers have no text.
+func (this *Encoder) textOf(o interface{}) (string, bool) {
+	switch o.(type) {
+	case time.Time, *time.Time:
+		return "", false
+	}
+	if f := reflect.ValueOf(o); f.Kind() == reflect.Ptr && f.IsNil() {
+		return "", false
+	}
+	switch m := o.(type) {
+	case encoding.TextMarshaler:
+		return this.marshalText(m), true
+	case fmt.Stringer:
+		return m.String(), true
+	}
+	return "", false
+}
+
+func (this *Encoder) marshalText(m encoding.TextMarshaler) string {
+	b, err := m.MarshalText()
+	if err != nil && this.err == nil {
+		this.err = fmt.Errorf("xmlrpc: error calling MarshalText for type %T: %w", m, err)
+	}
+	return string(b)
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// isKeyType reports whether map keys of type t can be written as member names:
+// strings, encoding.TextMarshalers and with TextStrings fmt.Stringers.
+func (this *Encoder) isKeyType(t reflect.Type) bool {
+	if t.Kind() == reflect.String || t.Implements(textMarshalerType) {
+		return true
+	}
+	return this.TextStrings && t.Implements(stringerType)
+}
+
+// keyName returns the member name of the map key k of a type accepted by isKeyType.
+// String keys are used as they are unless TextStrings is set.
+func (this *Encoder) keyName(k reflect.Value) string {
+	if k.Kind() == reflect.Ptr && k.IsNil() {
+		return ""
+	}
+	if this.TextStrings {
+		if s, ok := this.textOf(k.Interface()); ok {
+			return s
+		}
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok && k.Kind() != reflect.String {
+		return this.marshalText(m)
+	}
+	return k.String()
+}
@@ -0,0 +1,146 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type celsius float64
+
+type coord struct{ x, y int }
+
+func (c coord) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d/%d", c.x, c.y)), nil
+}
+
+func (c celsius) String() string {
+	return "warm"
+}
+
+func TestMarshalUnsupportedType(t *testing.T) {
+	type weather struct {
+		City  string             `xmlrpc:"city"`
+		Notes []interface{}      `xmlrpc:"notes"`
+		Temps map[int]float64    `xmlrpc:"temps"`
+		Ok    map[string]float64 `xmlrpc:"ok"`
+	}
+	for _, tt := range []struct {
+		args []interface{}
+		typ  reflect.Type
+		path string
+	}{
+		{[]interface{}{1, make(chan int)}, reflect.TypeOf(make(chan int)), "params[1]"},
+		{[]interface{}{weather{Temps: map[int]float64{1: 2}}}, reflect.TypeOf(map[int]float64{}), "params[0].temps"},
+		{[]interface{}{weather{}}, reflect.TypeOf(map[int]float64{}), "params[0].temps"},
+		{[]interface{}{&weather{Notes: []interface{}{"a", 1i}}}, reflect.TypeOf(1i), "params[0].notes[1]"},
+		{[]interface{}{map[string]interface{}{"f": func() {}}}, reflect.TypeOf(func() {}), "params[0].f"},
+		{[]interface{}{map[string]interface{}{"a.b": []interface{}{1i}}}, reflect.TypeOf(1i), `params[0]["a.b"][0]`},
+	} {
+		err := Marshal(new(bytes.Buffer), "test", tt.args...)
+		var unsupported *UnsupportedTypeError
+		if !errors.As(err, &unsupported) || unsupported.Type != tt.typ || unsupported.Path != tt.path {
+			t.Errorf("expected unsupported type %v at %s got %v", tt.typ, tt.path, err)
+		}
+	}
+
+	err := NewEncoder(new(bytes.Buffer)).EncodeResponse([]complex64{1})
+	if expected := "xmlrpc: unsupported type complex64 at params[0][0]"; err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+}
+
+func TestMarshalTextStrings(t *testing.T) {
+	v := struct {
+		IP    net.IP              `xmlrpc:"ip"`
+		Temp  celsius             `xmlrpc:"temp"`
+		Time  time.Time           `xmlrpc:"time"`
+		Tiles map[coord]string    `xmlrpc:"tiles"`
+		Temps map[celsius]celsius `xmlrpc:"temps"`
+	}{
+		IP:    net.IPv4(10, 0, 0, 1),
+		Temp:  20,
+		Time:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tiles: map[coord]string{{2, 3}: "sea"},
+		Temps: map[celsius]celsius{1: 2},
+	}
+
+	if err := NewEncoder(new(bytes.Buffer)).EncodeResponse(v); err == nil {
+		t.Errorf("expected error for map keys without TextStrings")
+	}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.TextStrings = true
+	if err := e.EncodeResponse(v); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"<name>ip</name><value><string>10.0.0.1</string></value>",
+		"<name>temp</name><value><string>warm</string></value>",
+		"<name>time</name><value><dateTime.iso8601>20240102T03:04:05</dateTime.iso8601></value>",
+		"<name>2/3</name><value><string>sea</string></value>",
+		"<name>warm</name><value><string>warm</string></value>",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in %s", expected, buf.String())
+		}
+	}
+}
+
+func TestMarshalMap(t *testing.T) {
+	m := map[string]interface{}{"zeta": 1, "alpha": "a", "mid": map[string]int{}}
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).EncodeResponse(m); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<param><value><struct>" +
+		"<member><name>alpha</name><value><string>a</string></value></member>" +
+		"<member><name>mid</name><value><struct></struct></value></member>" +
+		"<member><name>zeta</name><value><int>1</int></value></member>" +
+		"</struct></value></param>"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %s in %s", expected, buf.String())
+	}
+	var decoded map[string]interface{}
+	if err := UnmarshalResponse(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 || decoded["alpha"] != "a" || fmt.Sprint(decoded["zeta"]) != "1" {
+		t.Errorf("unexpected map %#v", decoded)
+	}
+
+	// output is reproducible
+	tiles := map[coord]int{{2, 1}: 3, {1, 5}: 2, {1, 2}: 1}
+	first := new(bytes.Buffer)
+	if err := Marshal(first, "test", tiles); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		if err := Marshal(buf, "test", tiles); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != first.String() {
+			t.Fatalf("expected %s got %s", first.String(), buf.String())
+		}
+	}
+	if !strings.Contains(first.String(), "<name>1/2</name><value><int>1</int></value></member><member><name>1/5</name>") {
+		t.Errorf("unexpected order in %s", first.String())
+	}
+
+	buf.Reset()
+	e := NewEncoder(buf)
+	e.UnsortedMaps = true
+	if err := e.EncodeResponse(tiles); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<member>"); n != 3 {
+		t.Errorf("expected 3 members got %d in %s", n, buf.String())
+	}
+}
@@ -0,0 +1,95 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+)
+
+// Marshaler is the interface implemented by types that encode themselves
+// as XML-RPC values. MarshalXMLRPC writes the content of exactly one
+// <value> element through e, e.g. with e.EncodeElement("string", s).
+type Marshaler interface {
+	MarshalXMLRPC(e *Encoder) error
+}
+
+// Unmarshaler is the interface implemented by types that decode themselves
+// from XML-RPC values. UnmarshalXMLRPC is called with the <value> start
+// element and must consume the tokens up to and including the matching end
+// element. It can read the raw token stream with d.Token or let the decoder
+// do the work with d.DecodeElement, for example into an interface{} to
+// receive the generically decoded value.
+type Unmarshaler interface {
+	UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// EncodeValue writes v as the content of a <value> element.
+func (this *Encoder) EncodeValue(v interface{}) error {
+	this.write(v)
+	return this.err
+}
+
+// EncodeElement writes a scalar element with the given tag name and
+// escaped text, e.g. EncodeElement("i4", "42") writes <i4>42</i4>.
+func (this *Encoder) EncodeElement(name string, text string) error {
+	this.openTag(tag(name))
+	this.writeText(text)
+	this.closeTag(tag(name))
+	return this.err
+}
+
+// Token returns the next XML token of the input stream.
+func (this *Decoder) Token() (xml.Token, error) {
+	return this.d.Token()
+}
+
+// Skip reads tokens until it has consumed the end element
+// matching the most recent start element already consumed.
+func (this *Decoder) Skip() error {
+	return this.d.Skip()
+}
+
+// DecodeElement decodes the content of the <value> element start into the
+// value pointed to by v, consuming the matching end element.
+// v must not be the Unmarshaler currently being called.
+func (this *Decoder) DecodeElement(v interface{}, start *xml.StartElement) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("xmlrpc: cannot unmarshal into non-pointer or nil %T", v)
+	}
+	if start.Name.Local != string(valueTag) {
+		return fmt.Errorf("xmlrpc: cannot decode element %s expected %s", start.Name.Local, valueTag)
+	}
+	return this.decodeValue(rv.Elem())
+}
+
+// findUnmarshaler walks down v like indirect and returns the first
+// value implementing Unmarshaler, allocating nil pointers on the way.
+func findUnmarshaler(v reflect.Value) Unmarshaler {
+	for {
+		if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+			return v.Addr().Interface().(Unmarshaler)
+		}
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			return nil
+		}
+		if v.IsNil() {
+			if !v.CanSet() || !v.Type().Implements(unmarshalerType) {
+				return nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
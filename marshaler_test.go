@@ -0,0 +1,103 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// cents encodes amounts of money as decimal strings.
+type cents int64
+
+func (this cents) MarshalXMLRPC(e *Encoder) error {
+	return e.EncodeElement("string", fmt.Sprintf("%d.%02d", this/100, this%100))
+}
+
+func (this *cents) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	var units, fraction int64
+	if _, err := fmt.Sscanf(s, "%d.%d", &units, &fraction); err != nil {
+		return err
+	}
+	*this = cents(units*100 + fraction)
+	return nil
+}
+
+// color decodes the type element of a value from the raw token stream.
+type color string
+
+func (this *color) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			*this = color(t.Name.Local)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type failing struct{}
+
+func (this failing) MarshalXMLRPC(e *Encoder) error {
+	return fmt.Errorf("not today")
+}
+
+func TestMarshaler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test.method", cents(1250), []cents{5}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		"<param><value><string>12.50</string></value></param>",
+		"<array><data><value><string>0.05</string></value></data></array>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %s in %s", want, s)
+		}
+	}
+
+	if err := Marshal(new(bytes.Buffer), "test.method", failing{}); err == nil || !strings.Contains(err.Error(), "not today") {
+		t.Errorf("expected marshaler error got %v", err)
+	}
+}
+
+func TestUnmarshaler(t *testing.T) {
+	s := `<methodResponse><params>
+		<param><value><string>12.50</string></value></param>
+		<param><value><struct>
+			<member><name>price</name><value><string>0.99</string></value></member>
+			<member><name>color</name><value><i4>7</i4></value></member>
+		</struct></value></param>
+	</params></methodResponse>`
+	var total cents
+	var item struct {
+		Price *cents
+		Color color
+	}
+	if err := UnmarshalResponse(bytes.NewBufferString(s), &total, &item); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if total != 1250 {
+		t.Errorf("expected %d got %d", 1250, total)
+	}
+	if item.Price == nil || *item.Price != 99 {
+		t.Errorf("unexpected price %v", item.Price)
+	}
+	if item.Color != "i4" {
+		t.Errorf("expected %s got %s", "i4", item.Color)
+	}
+}
@@ -0,0 +1,31 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// A Mode selects how strictly a Decoder follows the XML-RPC specification.
+type Mode int
+
+const (
+	// DefaultMode skips unknown elements, lets the last of duplicate struct
+	// members win and accepts several <params> in a response.
+	DefaultMode Mode = iota
+	// StrictMode rejects documents violating the specification: unknown
+	// elements, duplicate struct members, members without <name> or <value>
+	// and responses with more than one <params>.
+	StrictMode
+	// LenientMode is like DefaultMode but additionally accepts common deviations
+	// of other implementations: true and false as <boolean> and empty integers as 0.
+	LenientMode
+)
+
+// unknownElement handles the unexpected element start inside of parent,
+// which is skipped unless the decoder is in StrictMode.
+func (this *Decoder) unknownElement(start xml.StartElement, parent tag) error {
+	if this.Mode == StrictMode {
+		return fmt.Errorf("xmlrpc: unexpected element %s in %s", start.Name.Local, parent)
+	}
+	return this.d.Skip()
+}
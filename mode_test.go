@@ -0,0 +1,57 @@
+package xmlrpc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecoderModes(t *testing.T) {
+	response := func(value string) string {
+		return "<methodResponse><params><param><value>" + value + "</value></param></params></methodResponse>"
+	}
+	for _, tt := range []struct {
+		doc                     string
+		strict, normal, lenient bool // whether the document is accepted
+	}{
+		{response("<int>+5</int>"), true, true, true},
+		{response("<boolean>true</boolean>"), false, false, true},
+		{response("<boolean>FALSE</boolean>"), false, false, true},
+		{response("<int/>"), false, false, true},
+		{response("<int>1</int><foo/>"), false, false, false},
+		{response("<struct><foo>1</foo></struct>"), false, true, true},
+		{response("<array><data><value>1</value><comment>x</comment></data></array>"), false, true, true},
+		{response("<struct><member><name>a</name><value>1</value></member><member><name>a</name><value>2</value></member></struct>"), false, true, true},
+		{response("<struct><member><name>a</name></member></struct>"), false, true, true},
+		{response("<struct><member><value>1</value></member></struct>"), false, false, false},
+		{"<methodResponse><params><param><value>1</value></param></params><params/></methodResponse>", false, true, true},
+		{"<methodResponse><params><param><value>1</value><extra/></param></params></methodResponse>", false, true, true},
+	} {
+		for _, m := range []struct {
+			mode     Mode
+			accepted bool
+		}{{StrictMode, tt.strict}, {DefaultMode, tt.normal}, {LenientMode, tt.lenient}} {
+			for _, typed := range []bool{true, false} {
+				d := NewDecoder(strings.NewReader(tt.doc))
+				d.Mode = m.mode
+				var o interface{}
+				var err error
+				if typed {
+					err = d.DecodeResponse(&o)
+				} else {
+					err = d.read(&o)
+				}
+				if accepted := err == nil; accepted != m.accepted {
+					t.Errorf("mode %d typed %t: expected accepted %t for %s got err:%v", m.mode, typed, m.accepted, tt.doc, err)
+				}
+			}
+		}
+	}
+
+	var b bool
+	var i int
+	d := NewDecoder(strings.NewReader("<methodResponse><params><param><value><boolean> True </boolean></value></param><param><value><i4></i4></value></param></params></methodResponse>"))
+	d.Mode = LenientMode
+	if err := d.DecodeResponse(&b, &i); err != nil || !b || i != 0 {
+		t.Errorf("unexpected values %t %d err:%v", b, i, err)
+	}
+}
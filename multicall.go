@@ -0,0 +1,382 @@
+package xmlrpc
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const multicallMethod = "system.multicall"
+
+// A Batch queues calls and sends them as a single system.multicall request.
+// If the server does not support system.multicall the calls are sent one after another.
+type Batch struct {
+	client Client
+	calls  []*BatchCall
+}
+
+// A BatchCall is a call queued in a Batch.
+type BatchCall struct {
+	Method string
+	Args   []interface{}
+	// Result points to the value the response is decoded into, it may be nil.
+	Result interface{}
+	// Err is the fault or error of the call once the batch has run.
+	Err error
+}
+
+// multicallEntry is the struct describing a single call of system.multicall.
+type multicallEntry struct {
+	MethodName string        `xmlrpc:"methodName"`
+	Params     []interface{} `xmlrpc:"params"`
+}
+
+// NewBatch returns an empty batch calling c.
+func NewBatch(c Client) *Batch {
+	return &Batch{client: c}
+}
+
+// Add queues a call of method with args whose response is decoded into
+// the value pointed to by result like CallInto.
+func (this *Batch) Add(result interface{}, method string, args ...interface{}) *BatchCall {
+	call := &BatchCall{Method: method, Args: args, Result: result}
+	this.calls = append(this.calls, call)
+	return call
+}
+
+// Calls returns the queued calls in order.
+func (this *Batch) Calls() []*BatchCall {
+	return this.calls
+}
+
+// Run sends the queued calls. The results and faults of the single calls are
+// stored in their BatchCall, Run only returns errors of the multicall itself.
+func (this *Batch) Run() error {
+	return this.RunContext(context.Background())
+}
+
+// RunContext is like Run with the cancellation of CallContext.
+func (this *Batch) RunContext(ctx context.Context) error {
+	if len(this.calls) == 0 {
+		return nil
+	}
+	entries := make([]multicallEntry, len(this.calls))
+	for i, call := range this.calls {
+		entries[i] = multicallEntry{MethodName: call.Method, Params: call.Args}
+		call.Err = nil
+	}
+	err := this.client.CallIntoContext(ctx, &multicallResults{calls: this.calls}, multicallMethod, entries)
+	var fault *Fault
+	if errors.As(err, &fault) && isUnsupportedMulticall(fault) {
+		for _, call := range this.calls {
+			call.Err = this.client.CallIntoContext(ctx, call.Result, call.Method, call.Args...)
+		}
+		return nil
+	}
+	return err
+}
+
+// isUnsupportedMulticall reports whether fault tells that the server does not know system.multicall.
+// Besides the interoperability fault code the messages of common servers are recognized.
+func isUnsupportedMulticall(fault *Fault) bool {
+	if fault.Code == FaultMethodNotFound {
+		return true
+	}
+	s := strings.ToLower(fault.String)
+	if !strings.Contains(s, multicallMethod) {
+		return false
+	}
+	for _, phrase := range []string{"not supported", "unsupported", "not found", "no such"} {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// multicallResults decodes the array answered by system.multicall into the results of calls.
+type multicallResults struct {
+	calls []*BatchCall
+}
+
+func (this *multicallResults) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	i := 0
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local == string(valueTag) {
+				if i >= len(this.calls) {
+					return fmt.Errorf("xmlrpc: %s returned more than %d results", multicallMethod, len(this.calls))
+				}
+				if err := d.DecodeElement(&multicallResult{call: this.calls[i]}, &t); err != nil {
+					return err
+				}
+				i++
+			}
+		case xml.EndElement:
+			// the end of nested values is consumed by DecodeElement
+			if t.Name.Local == string(valueTag) {
+				if i != len(this.calls) {
+					return fmt.Errorf("xmlrpc: %s returned %d results for %d calls", multicallMethod, i, len(this.calls))
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// multicallResult decodes a single element of the system.multicall response, which is
+// either an array holding the result of the call or a fault struct.
+type multicallResult struct {
+	call *BatchCall
+}
+
+func (this *multicallResult) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	decoded := false
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case string(structTag):
+				var n interface{}
+				if err := d.decodeStruct(reflect.ValueOf(&n).Elem()); err != nil {
+					return err
+				}
+				this.call.Err = newFault(n)
+			case string(valueTag):
+				if decoded {
+					if err := d.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+				result := this.call.Result
+				if result == nil {
+					result = new(interface{})
+				}
+				// a result not matching its target only fails its call
+				var raw RawValue
+				if err := d.DecodeElement(&raw, &t); err != nil {
+					return err
+				}
+				this.call.Err = d.decodeRaw(raw, result)
+				decoded = true
+			}
+		case xml.EndElement:
+			if t.Name.Local == string(valueTag) {
+				return nil
+			}
+		}
+	}
+}
+
+// multicall answers a system.multicall request with an array holding a
+// single element array with the result or a fault struct for each call.
+func (this *Server) multicall(ctx context.Context, decode func([]reflect.Value) (int, error)) (interface{}, error) {
+	calls := multicallCalls{max: this.MaxMulticall}
+	if calls.max <= 0 {
+		calls.max = DefaultMaxMulticall
+	}
+	if err := decodeArgs(multicallMethod, []reflect.Value{reflect.ValueOf(&calls).Elem()}, decode); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(calls.calls))
+	for i, call := range calls.calls {
+		var (
+			result interface{}
+			err    error
+		)
+		switch {
+		case call.err != nil:
+			err = call.err
+		case call.method == multicallMethod:
+			err = &Fault{Code: FaultInvalidRequest, String: fmt.Sprintf("recursive %s is not allowed", multicallMethod)}
+		default:
+			result, err = this.call(ctx, call.method, call.decodeParams)
+		}
+		if err == nil {
+			// a result that cannot be encoded only fails its call
+			if encErr := NewEncoder(io.Discard).EncodeResponse(result); encErr != nil {
+				err = &Fault{Code: FaultInternalError, String: fmt.Sprintf("cannot encode result: %v", encErr)}
+			}
+		}
+		if err != nil {
+			code, message := faultOf(err)
+			results[i] = Fault{Code: code, String: message}
+		} else {
+			results[i] = []interface{}{result}
+		}
+	}
+	return results, nil
+}
+
+// multicallCalls decodes the calls of a system.multicall request and fails
+// as soon as there are more than max calls, before recording their params.
+type multicallCalls struct {
+	calls []multicallCall
+	max   int
+}
+
+func (this *multicallCalls) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case string(arrayTag), string(dataTag):
+				// continue
+			case string(valueTag):
+				n := len(this.calls) + 1
+				if n > this.max {
+					return &Fault{Code: FaultInvalidRequest, String: fmt.Sprintf("%s with more than %d calls exceeds the maximum", multicallMethod, this.max)}
+				}
+				if err := d.checkMembers(n); err != nil {
+					return err
+				}
+				this.calls = append(this.calls, multicallCall{})
+				d.push(fmt.Sprintf("[%d]", n-1))
+				if err := d.DecodeElement(&this.calls[n-1], &t); err != nil {
+					return err
+				}
+				d.pop()
+			default:
+				return &TypeError{Value: t.Name.Local, Type: reflect.TypeOf(this.calls)}
+			}
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				return nil
+			}
+		}
+	}
+}
+
+// multicallCall is a call of a system.multicall request. The tokens of its
+// params are recorded while decoding, as their types depend on the method.
+type multicallCall struct {
+	method string
+	params []xml.Token
+	err    error
+	d      *Decoder
+}
+
+func (this *multicallCall) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	var m struct {
+		MethodName string      `xmlrpc:"methodName"`
+		Params     tokenRecord `xmlrpc:"params"`
+	}
+	if err := d.DecodeElement(&m, &start); err != nil {
+		return err
+	}
+	if m.MethodName == "" {
+		this.err = &Fault{Code: FaultInvalidRequest, String: fmt.Sprintf("%s entry without methodName", multicallMethod)}
+	}
+	this.method, this.params, this.d = m.MethodName, m.Params, d
+	return nil
+}
+
+// decodeParams decodes the recorded params array into args.
+func (this *multicallCall) decodeParams(args []reflect.Value) (int, error) {
+	return this.d.replay(this.params).decodeArrayList(args)
+}
+
+// tokenRecord records the tokens of a value's content for decoding them later.
+type tokenRecord []xml.Token
+
+func (this *tokenRecord) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+		*this = append(*this, xml.CopyToken(t))
+	}
+}
+
+// replay returns a decoder with the settings of this reading the recorded tokens.
+func (this *Decoder) replay(tokens []xml.Token) *Decoder {
+	d := *this
+	d.d, d.in = xml.NewTokenDecoder(&tokenReplay{tokens: tokens}), nil
+	return &d
+}
+
+// tokenReplay is an xml.TokenReader returning recorded tokens.
+type tokenReplay struct {
+	tokens []xml.Token
+}
+
+func (this *tokenReplay) Token() (xml.Token, error) {
+	if len(this.tokens) == 0 {
+		return nil, io.EOF
+	}
+	t := this.tokens[0]
+	this.tokens = this.tokens[1:]
+	return t, nil
+}
+
+// decodeArrayList decodes the i'th element of an <array> into dst[i], skips
+// elements beyond len(dst) and returns the number of elements.
+func (this *Decoder) decodeArrayList(dst []reflect.Value) (int, error) {
+	i := 0
+	for {
+		t, err := this.d.Token()
+		if err == io.EOF && i == 0 {
+			// no params at all
+			return 0, nil
+		}
+		if err != nil {
+			return i, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case string(arrayTag), string(dataTag):
+				// continue
+			case string(valueTag):
+				if err := this.checkMembers(i + 1); err != nil {
+					return i, err
+				}
+				if i < len(dst) {
+					err = this.decodeValue(dst[i])
+				} else {
+					err = this.d.Skip()
+				}
+				if err != nil {
+					return i, err
+				}
+				i++
+			default:
+				return i, &TypeError{Value: t.Name.Local, Type: reflect.TypeOf([]interface{}{})}
+			}
+		case xml.EndElement:
+			if t.Name.Local == string(arrayTag) {
+				return i, nil
+			}
+		}
+	}
+}
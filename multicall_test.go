@@ -0,0 +1,196 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	srv := NewServer()
+	// system.multicall answering math.add calls and faults for everything else
+	srv.RegisterFunc("system.multicall", func(calls []multicallEntry) []interface{} {
+		results := make([]interface{}, len(calls))
+		for i, call := range calls {
+			if call.MethodName == "math.add" {
+				results[i] = []interface{}{call.Params[0].(int64) + call.Params[1].(int64)}
+			} else {
+				results[i] = Fault{Code: FaultMethodNotFound, String: "no " + call.MethodName}
+			}
+		}
+		return results
+	})
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var sum1, sum2 int
+	b := NewBatch(c)
+	c1 := b.Add(&sum1, "math.add", 1, 2)
+	c2 := b.Add(nil, "math.sub", 1, 2)
+	c3 := b.Add(&sum2, "math.add", 3, 4)
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c1.Err != nil || sum1 != 3 || c3.Err != nil || sum2 != 7 {
+		t.Errorf("unexpected results %d %v, %d %v", sum1, c1.Err, sum2, c3.Err)
+	}
+	var fault *Fault
+	if !errors.As(c2.Err, &fault) || fault.String != "no math.sub" {
+		t.Errorf("expected fault got %v", c2.Err)
+	}
+	if len(b.Calls()) != 3 {
+		t.Errorf("expected %d calls got %d", 3, len(b.Calls()))
+	}
+}
+
+func TestBatchResultTypeError(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("name", func() string { return "gopher" })
+	srv.RegisterFunc("num", func() int { return 42 })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var i1, i2 int
+	b := NewBatch(c)
+	c1 := b.Add(&i1, "name")
+	c2 := b.Add(&i2, "num")
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var typeErr *TypeError
+	if !errors.As(c1.Err, &typeErr) {
+		t.Errorf("expected type error got %v", c1.Err)
+	}
+	if c2.Err != nil || i2 != 42 {
+		t.Errorf("unexpected result %d %v", i2, c2.Err)
+	}
+}
+
+func TestBatchFallback(t *testing.T) {
+	srv := NewServer()
+	multicalls := 0
+	srv.RegisterFunc("system.multicall", func(calls []interface{}) error {
+		multicalls++
+		return &Fault{Code: FaultMethodNotFound, String: "system.multicall not supported"}
+	})
+	calls := 0
+	srv.RegisterFunc("math.add", func(a, b int) int {
+		calls++
+		return a + b
+	})
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var sum1, sum2 int
+	b := NewBatch(c)
+	c1 := b.Add(&sum1, "math.add", 1, 2)
+	c2 := b.Add(&sum2, "math.add", 3, "x")
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c1.Err != nil || sum1 != 3 {
+		t.Errorf("unexpected result %d %v", sum1, c1.Err)
+	}
+	var fault *Fault
+	if !errors.As(c2.Err, &fault) || fault.Code != FaultInvalidParams {
+		t.Errorf("expected fault %d got %v", FaultInvalidParams, c2.Err)
+	}
+	if multicalls != 1 || calls != 1 {
+		t.Errorf("expected %d multicall and %d sequential calls got %d and %d", 1, 1, multicalls, calls)
+	}
+}
+
+func TestServerMulticall(t *testing.T) {
+	srv := NewServer()
+	srv.Register("blog", &blogService{posts: map[int]post{1: {1, "Hello"}}})
+	srv.RegisterFunc("math.add", func(a, b int) int { return a + b })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var p post
+	var sum int
+	var count int
+	b := NewBatch(c)
+	c1 := b.Add(&p, "blog.getPost", 1)
+	c2 := b.Add(&sum, "math.add", 1, 2)
+	c3 := b.Add(nil, "blog.getPost", 2)
+	c4 := b.Add(nil, "math.add", "x", 2)
+	c5 := b.Add(nil, "system.multicall", []interface{}{})
+	c6 := b.Add(&count, "blog.countPosts")
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c1.Err != nil || p.Title != "Hello" || c2.Err != nil || sum != 3 || c6.Err != nil || count != 1 {
+		t.Errorf("unexpected results %+v %v, %d %v, %d %v", p, c1.Err, sum, c2.Err, count, c6.Err)
+	}
+	for _, c := range []struct {
+		call *BatchCall
+		code int
+	}{{c3, 404}, {c4, FaultInvalidParams}, {c5, FaultInvalidRequest}} {
+		var fault *Fault
+		if !errors.As(c.call.Err, &fault) || fault.Code != c.code {
+			t.Errorf("%s: expected fault %d got %v", c.call.Method, c.code, c.call.Err)
+		}
+	}
+
+	srv.MaxMulticall = 2
+	b = NewBatch(c)
+	for i := 0; i < 3; i++ {
+		b.Add(nil, "math.add", 1, 2)
+	}
+	var fault *Fault
+	if err := b.Run(); !errors.As(err, &fault) || fault.Code != FaultInvalidRequest {
+		t.Errorf("expected fault %d got %v", FaultInvalidRequest, err)
+	}
+}
+
+func TestServerMulticallLimit(t *testing.T) {
+	srv := NewServer()
+	srv.MaxMulticall = 2
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// the document is cut off after the third call, which is only
+	// answered with the limit fault if decoding stops there
+	entry := "<value><struct><member><name>methodName</name><value>math.add</value></member>" +
+		"<member><name>params</name><value><array><data></data></array></value></member></struct></value>"
+	doc := xml.Header + "<methodCall><methodName>system.multicall</methodName><params><param><value><array><data>" +
+		strings.Repeat(entry, 3) + "<value><struct><member>"
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var o interface{}
+	err = UnmarshalResponse(resp.Body, &o)
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Code != FaultInvalidRequest {
+		t.Errorf("expected fault %d got %v", FaultInvalidRequest, err)
+	}
+}
+
+func TestServerMulticallUnencodableResult(t *testing.T) {
+	srv := NewServer()
+	srv.RegisterFunc("feed", func() chan int { return make(chan int) })
+	srv.RegisterFunc("math.add", func(a, b int) int { return a + b })
+	c, done := newTestClient(t, srv)
+	defer done()
+
+	var sum int
+	b := NewBatch(c)
+	c1 := b.Add(nil, "feed")
+	c2 := b.Add(&sum, "math.add", 1, 2)
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var fault *Fault
+	if !errors.As(c1.Err, &fault) || fault.Code != FaultInternalError {
+		t.Errorf("expected fault %d got %v", FaultInternalError, c1.Err)
+	}
+	if c2.Err != nil || sum != 3 {
+		t.Errorf("unexpected result %d %v", sum, c2.Err)
+	}
+}
@@ -0,0 +1,112 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A NilPolicy selects how an Encoder writes nil pointers, maps, slices and interfaces.
+type NilPolicy int
+
+const (
+	// NilValue writes <nil/>, or <ex:nil/> with Extensions, for nil pointers and
+	// interfaces. Nil slices and maps are written like empty ones, as <nil/> is
+	// not part of the specification.
+	NilValue NilPolicy = iota
+	// NilOmit leaves out struct members that are nil. Where a value cannot be
+	// left out, in arrays and params, the empty value is written like NilEmpty.
+	NilOmit
+	// NilEmpty writes the empty value of the type for peers not supporting
+	// <nil/>: the zero value of a pointer's element type, an empty array for
+	// slices, an empty struct for maps and an empty <value> for interfaces.
+	NilEmpty
+	// NilValueAll is like NilValue but writes <nil/> for nil slices and maps as well.
+	NilValueAll
+)
+
+// ref identifies a pointer, map or slice on the path of the value being written.
+type ref struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// writeNilOf writes the nil value of type t according to the NilPolicy,
+// t is nil for a nil interface.
+func (this *Encoder) writeNilOf(t reflect.Type) {
+	switch this.NilPolicy {
+	case NilValue:
+		if t == nil || t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+			this.writeNil()
+			return
+		}
+	case NilValueAll:
+		this.writeNil()
+		return
+	}
+	// the empty value of a recursive type ends at the recursion
+	if t == nil || this.emptying[t] {
+		return
+	}
+	if this.emptying == nil {
+		this.emptying = make(map[reflect.Type]bool)
+	}
+	this.emptying[t] = true
+	defer delete(this.emptying, t)
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		this.write(reflect.New(t.Elem()).Interface())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			this.writeBytes(nil)
+		} else {
+			this.openTag(arrayTag)
+			this.openTag(dataTag)
+			this.closeTag(dataTag)
+			this.closeTag(arrayTag)
+		}
+	case reflect.Map:
+		this.openTag(structTag)
+		this.closeTag(structTag)
+	}
+}
+
+// isNilValue reports whether v is a nil pointer, map, slice or interface.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// enterRef records the pointer, map or slice f on the path of the value being
+// written. It fails if f is already on the path, so f refers to itself.
+// Each successful call has to be followed by a call of leaveRef.
+func (this *Encoder) enterRef(f reflect.Value) bool {
+	r := refOf(f)
+	if this.visiting[r] {
+		if this.err == nil {
+			this.err = fmt.Errorf("xmlrpc: cannot encode cyclic value via %s", f.Type())
+		}
+		return false
+	}
+	if this.visiting == nil {
+		this.visiting = make(map[ref]bool)
+	}
+	this.visiting[r] = true
+	return true
+}
+
+func (this *Encoder) leaveRef(f reflect.Value) {
+	delete(this.visiting, refOf(f))
+}
+
+func refOf(f reflect.Value) ref {
+	r := ref{ptr: f.Pointer(), typ: f.Type()}
+	if f.Kind() == reflect.Slice {
+		r.len = f.Len()
+	}
+	return r
+}
@@ -0,0 +1,142 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type nilNode struct {
+	Name string      `xmlrpc:"name"`
+	Next *nilNode    `xmlrpc:"next"`
+	Tags []string    `xmlrpc:"tags"`
+	Any  interface{} `xmlrpc:"any"`
+}
+
+func encodeResponse(t *testing.T, e func(*Encoder), v interface{}) (string, error) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	if e != nil {
+		e(enc)
+	}
+	err := enc.EncodeResponse(v)
+	return buf.String(), err
+}
+
+func TestEncodePointers(t *testing.T) {
+	n := 7
+	s := "text"
+	node := &nilNode{Name: "a", Next: &nilNode{Name: "b"}}
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test", node, []interface{}{&n, &s, &node}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded nilNode
+	var list []interface{}
+	if _, err := UnmarshalCall(buf, &decoded, &list); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "a" || decoded.Next == nil || decoded.Next.Name != "b" || decoded.Next.Next != nil {
+		t.Errorf("unexpected node %+v", decoded)
+	}
+	if len(list) != 3 || fmt.Sprint(list[0]) != "7" || list[1] != "text" {
+		t.Errorf("unexpected list %#v", list)
+	}
+	if m, ok := list[2].(map[string]interface{}); !ok || m["name"] != "a" {
+		t.Errorf("unexpected pointer to pointer %#v", list[2])
+	}
+}
+
+func TestEncodeNilPolicy(t *testing.T) {
+	node := nilNode{Name: "a"}
+	for _, tt := range []struct {
+		policy   NilPolicy
+		expected []string
+		missing  []string
+	}{
+		{NilValue, []string{
+			"<member><name>next</name><value><nil/></value></member>",
+			"<member><name>tags</name><value><array><data></data></array></value></member>",
+			"<member><name>any</name><value><nil/></value></member>",
+		}, nil},
+		{NilValueAll, []string{
+			"<member><name>next</name><value><nil/></value></member>",
+			"<member><name>tags</name><value><nil/></value></member>",
+			"<member><name>any</name><value><nil/></value></member>",
+		}, nil},
+		{NilOmit, nil, []string{"next", "tags", "any", "<nil/>"}},
+		{NilEmpty, []string{
+			// the empty value of the recursive type ends at the recursion
+			"<member><name>next</name><value><struct><member><name>name</name><value><string></string></value></member>" +
+				"<member><name>next</name><value></value></member><member><name>tags</name><value><array><data></data></array></value></member>" +
+				"<member><name>any</name><value></value></member></struct></value></member>",
+			"<member><name>tags</name><value><array><data></data></array></value></member>",
+			"<member><name>any</name><value></value></member>",
+		}, []string{"<nil/>"}},
+	} {
+		s, err := encodeResponse(t, func(e *Encoder) { e.NilPolicy = tt.policy }, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range tt.expected {
+			if !strings.Contains(s, expected) {
+				t.Errorf("policy %d: expected %s in %s", tt.policy, expected, s)
+			}
+		}
+		for _, missing := range tt.missing {
+			if strings.Contains(s, missing) {
+				t.Errorf("policy %d: unexpected %s in %s", tt.policy, missing, s)
+			}
+		}
+	}
+
+	// values which cannot be left out are written empty
+	s, err := encodeResponse(t, func(e *Encoder) { e.NilPolicy = NilOmit }, []interface{}{nil, []byte(nil), map[string]int(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<array><data><value></value><value><base64></base64></value><value><struct></struct></value></data></array>"; !strings.Contains(s, expected) {
+		t.Errorf("expected %s in %s", expected, s)
+	}
+
+	// nil slices and maps stay within the specification by default
+	s, err = encodeResponse(t, nil, []interface{}{[]int(nil), []byte(nil), map[string]int(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<array><data><value><array><data></data></array></value><value><base64></base64></value><value><struct></struct></value></data></array>"; !strings.Contains(s, expected) {
+		t.Errorf("expected %s in %s", expected, s)
+	}
+
+	// nil is written once without continuing with the value
+	s, err = encodeResponse(t, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<param><value><nil/></value></param>"; !strings.Contains(s, expected) {
+		t.Errorf("expected %s in %s", expected, s)
+	}
+}
+
+func TestEncodeCycle(t *testing.T) {
+	node := &nilNode{Name: "a"}
+	node.Next = node
+	list := []interface{}{1, nil}
+	list[1] = list
+	m := map[string]interface{}{}
+	m["self"] = m
+	for _, v := range []interface{}{node, list, m} {
+		if _, err := encodeResponse(t, nil, v); err == nil || !strings.Contains(err.Error(), "cyclic") {
+			t.Errorf("%T: expected cycle error got %v", v, err)
+		}
+	}
+
+	// shared values are no cycle
+	shared := &nilNode{Name: "b"}
+	tags := []string{"x"}
+	if _, err := encodeResponse(t, nil, []interface{}{shared, shared, tags, tags}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
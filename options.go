@@ -0,0 +1,97 @@
+package xmlrpc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A ClientOption configures a client created by NewClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	client    *http.Client
+	header    http.Header
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	limits    Limits
+	mode      Mode
+}
+
+// WithHTTPClient makes the client send its requests with c instead of a
+// new http.Client. c itself is not modified by the other options.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.client = c
+	}
+}
+
+// WithHeader adds the header key with value to each request.
+func WithHeader(key, value string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.header.Add(key, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header of each request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.header.Set("User-Agent", userAgent)
+	}
+}
+
+// WithTimeout limits the time of each call including reading the response.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithTLSConfig makes the client use config for HTTPS connections,
+// e.g. with custom root CAs or client certificates for mutual TLS.
+// The transport of the http.Client must be an *http.Transport.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.tlsConfig = config
+	}
+}
+
+// WithLimits restricts the size of the responses decoded by the client.
+func WithLimits(limits Limits) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.limits = limits
+	}
+}
+
+// WithMode sets how strictly responses have to follow the specification.
+func WithMode(mode Mode) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.mode = mode
+	}
+}
+
+// httpClient returns the http.Client with timeout and TLS config applied.
+func (this *clientConfig) httpClient() (*http.Client, error) {
+	client := new(http.Client)
+	if this.client != nil {
+		*client = *this.client
+	}
+	if this.timeout > 0 {
+		client.Timeout = this.timeout
+	}
+	if this.tlsConfig != nil {
+		rt := client.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		transport, ok := rt.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("xmlrpc: cannot apply TLS config to transport of type %T", rt)
+		}
+		transport = transport.Clone()
+		transport.TLSClientConfig = this.tlsConfig
+		client.Transport = transport
+	}
+	return client, nil
+}
@@ -0,0 +1,146 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// A RawValue holds the verbatim XML of a <value> element, including the
+// element itself. Decoding into a RawValue defers the decoding of the value,
+// which can be done later with Decode, and encoding a RawValue writes it
+// unchanged. A nil RawValue is encoded like a nil interface according to the
+// NilPolicy of the Encoder.
+//
+// Prefixes of namespaces declared outside of the value are not resolved
+// when decoding it later, except for the ex prefix of the extension types.
+type RawValue []byte
+
+var (
+	rawValueStart = []byte("<value>")
+	rawValueEnd   = []byte("</value>")
+	rawValueEmpty = []byte("<value/>")
+)
+
+// Decode decodes the value into the value pointed to by v like UnmarshalResponse decodes a param.
+func (this RawValue) Decode(v interface{}) error {
+	return NewDecoder(bytes.NewReader(this)).decodeFirst(v)
+}
+
+// decodeRaw decodes raw into v with the settings of this.
+func (this *Decoder) decodeRaw(raw RawValue, v interface{}) error {
+	d := *this
+	d.in = &limitReader{r: bufio.NewReader(bytes.NewReader(raw)), d: &d}
+	d.d = xml.NewDecoder(d.in)
+	d.d.CharsetReader = d.CharsetReader
+	d.depth, d.path = 0, nil
+	return d.decodeFirst(v)
+}
+
+// decodeFirst decodes the first element of the input into v.
+func (this *Decoder) decodeFirst(v interface{}) (err error) {
+	defer this.annotate(&err)
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			return this.DecodeElement(v, &start)
+		}
+	}
+}
+
+func (this RawValue) MarshalXMLRPC(e *Encoder) error {
+	switch {
+	case len(this) == 0:
+		e.writeNilOf(nil)
+	case bytes.Equal(this, rawValueEmpty):
+		// empty string
+	case bytes.HasPrefix(this, rawValueStart) && bytes.HasSuffix(this, rawValueEnd) && this.wellFormed():
+		e.writeRaw(string(this[len(rawValueStart) : len(this)-len(rawValueEnd)]))
+	default:
+		return fmt.Errorf("xmlrpc: RawValue is not a single value element")
+	}
+	return e.err
+}
+
+// wellFormed reports whether the bytes are well-formed XML ending with the
+// end of the first element, so nothing can be written next to the value.
+func (this RawValue) wellFormed() bool {
+	d := xml.NewDecoder(bytes.NewReader(this))
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return depth == 0
+		}
+		if err != nil {
+			return false
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 && d.InputOffset() != int64(len(this)) {
+				return false
+			}
+		}
+	}
+}
+
+func (this *RawValue) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	raw, err := d.readRaw()
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		// <value/>
+		*this = append((*this)[:0], rawValueEmpty...)
+		return nil
+	}
+	*this = append(append((*this)[:0], rawValueStart...), raw...)
+	return nil
+}
+
+// readRaw consumes the tokens up to and including the end of the current
+// element and returns their XML. The input is recorded if possible, else
+// the tokens are written again, e.g. for tokens replayed by multicall.
+func (this *Decoder) readRaw() ([]byte, error) {
+	if in := this.in; in != nil && !in.converted {
+		in.recording, in.record = true, in.record[:0]
+		err := this.d.Skip()
+		in.recording = false
+		return append([]byte(nil), in.record...), err
+	}
+
+	buf := new(bytes.Buffer)
+	e := xml.NewEncoder(buf)
+	// the start element makes the encoder accept the end element
+	if err := e.EncodeToken(xml.StartElement{Name: xml.Name{Local: string(valueTag)}}); err != nil {
+		return nil, err
+	}
+	depth := 0
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		if err := e.EncodeToken(t); err != nil {
+			return nil, err
+		}
+		if depth < 0 {
+			err = e.Flush()
+			return buf.Bytes()[len(rawValueStart):], err
+		}
+	}
+}
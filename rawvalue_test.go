@@ -0,0 +1,125 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRawValue(t *testing.T) {
+	type blog struct {
+		Title string   `xmlrpc:"title"`
+		Posts RawValue `xmlrpc:"posts"`
+	}
+	posts := `<value><array><data>
+		<value><struct><member><name>postId</name><value><i4>1</i4></value></member><member><name>title</name><value>Hello &amp; welcome</value></member></struct></value>
+		<value><struct><member><name>postId</name><value><i4>2</i4></value></member><member><name>title</name><value><string><![CDATA[<b>bold</b>]]></string></value></member></struct></value>
+		</data></array></value>`
+	s := `<?xml version="1.0"?>
+		<methodResponse>
+		  <params>
+		    <param><value><struct><member><name>title</name><value>Blog</value></member><member><name>posts</name>` + posts + `</member></struct></value></param>
+		    <param><value><ex:i8>42</ex:i8></value></param>
+		    <param><value/></param>
+		  </params>
+		</methodResponse>`
+
+	var b blog
+	var i8, empty RawValue
+	if err := UnmarshalResponse(strings.NewReader(s), &b, &i8, &empty); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if b.Title != "Blog" || string(b.Posts) != posts {
+		t.Errorf("unexpected values %s %s", b.Title, b.Posts)
+	}
+	if string(i8) != "<value><ex:i8>42</ex:i8></value>" || string(empty) != "<value/>" {
+		t.Errorf("unexpected raw values %s %s", i8, empty)
+	}
+
+	var p []post
+	if err := b.Posts.Decode(&p); err != nil {
+		t.Fatalf("error decoding err:%v", err)
+	}
+	if len(p) != 2 || p[0].Title != "Hello & welcome" || p[1].ID != 2 || p[1].Title != "<b>bold</b>" {
+		t.Errorf("unexpected posts %+v", p)
+	}
+	var n int64
+	if err := i8.Decode(&n); err != nil || n != 42 {
+		t.Errorf("unexpected value %d err:%v", n, err)
+	}
+	var o interface{}
+	if err := empty.Decode(&o); err != nil || o != "" {
+		t.Errorf("unexpected value %#v err:%v", o, err)
+	}
+	var wrong string
+	if err := i8.Decode(&wrong); err == nil {
+		t.Errorf("expected error decoding int into string")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "blog.save", b, empty, RawValue(nil)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<member><name>posts</name>"+posts+"</member>") ||
+		!strings.Contains(out, "<param><value></value></param>") || !strings.Contains(out, "<param><value><nil/></value></param>") {
+		t.Errorf("unexpected document %s", out)
+	}
+	if err := Marshal(buf, "blog.save", RawValue("<int>1</int>")); err == nil {
+		t.Errorf("expected error encoding invalid RawValue")
+	}
+
+	// params recorded by system.multicall are written again from their tokens
+	srv := NewServer()
+	srv.RegisterFunc("echo", func(v RawValue) RawValue { return v })
+	c, done := newTestClient(t, srv)
+	defer done()
+	var echoed []post
+	batch := NewBatch(c)
+	call := batch.Add(&echoed, "echo", p)
+	if err := batch.Run(); err != nil || call.Err != nil {
+		t.Fatalf("unexpected error %v %v", err, call.Err)
+	}
+	if len(echoed) != 2 || echoed[1].Title != "<b>bold</b>" {
+		t.Errorf("unexpected posts %+v", echoed)
+	}
+}
+
+func TestRawValueMarshalInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"<value><string>a</string></value><injected/><value></value>",
+		"<value></value><value></value>",
+		"<value><string>a</value>",
+		"<value><string>a</string>",
+		"<value>&bogus;</value>",
+	} {
+		buf := new(bytes.Buffer)
+		if err := Marshal(buf, "test", RawValue(raw)); err == nil {
+			t.Errorf("%s: expected error got %s", raw, buf.String())
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	raw := RawValue("<value><array><data><value><i4>1</i4></value><value>x</value></data></array></value>")
+	if err := Marshal(buf, "test", raw); err != nil || !strings.Contains(buf.String(), "<param>"+string(raw)+"</param>") {
+		t.Errorf("unexpected output %s err:%v", buf.String(), err)
+	}
+}
+
+func TestRawValueNilPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		policy   NilPolicy
+		expected string
+	}{
+		{NilValue, "<param><value><nil/></value></param>"},
+		{NilEmpty, "<param><value></value></param>"},
+		{NilOmit, "<param><value></value></param>"},
+	} {
+		buf := new(bytes.Buffer)
+		e := NewEncoder(buf)
+		e.NilPolicy = tt.policy
+		if err := e.EncodeResponse(RawValue(nil)); err != nil || !strings.Contains(buf.String(), tt.expected) {
+			t.Errorf("policy %d: expected %s in %s err:%v", tt.policy, tt.expected, buf.String(), err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// A Server is an http.Handler answering XML-RPC calls with registered Go functions.
+//
+// A function can take a context.Context as first argument, which is the
+// context of the HTTP request, followed by any number of arguments decoded
+// from the params of the call. It can return a result, an error, or a result
+// followed by an error. An error is answered with a fault, using the code of a
+// *Fault or FaultApplicationError otherwise. Functions without a result answer <nil/>.
+//
+// The server answers system.multicall and the introspection methods
+// system.listMethods, system.methodSignature and system.methodHelp
+// unless functions are registered under these names.
+type Server struct {
+	// MaxMulticall limits the number of calls in a system.multicall request,
+	// 0 means DefaultMaxMulticall.
+	MaxMulticall int
+
+	// Limits restricts the size of the requests decoded, nil means DefaultLimits.
+	Limits *Limits
+
+	mu      sync.RWMutex
+	methods map[string]*method
+}
+
+// DefaultMaxMulticall is the default maximum number of calls in a system.multicall request.
+const DefaultMaxMulticall = 1000
+
+type method struct {
+	fn        reflect.Value
+	args      []reflect.Type // argument types without the context
+	hasCtx    bool
+	hasResult bool
+	hasErr    bool
+	help      string
+}
+
+// A MethodOption configures a function registered with RegisterFunc.
+type MethodOption func(*method)
+
+// WithHelp sets the description answered by system.methodHelp.
+func WithHelp(help string) MethodOption {
+	return func(m *method) {
+		m.help = help
+	}
+}
+
+// A MethodHelper is implemented by receivers passed to Register that describe
+// their methods. MethodHelp returns the description answered by system.methodHelp
+// for the Go method called method, e.g. GetPost. It is not published itself.
+type MethodHelper interface {
+	MethodHelp(method string) string
+}
+
+// NewServer returns a server without registered methods.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]*method)}
+}
+
+// Register publishes the exported methods of rcvr with a suitable signature
+// as name.method, where the first letter of the Go method name is lower
+// cased, e.g. GetPost of Register("blog", &BlogService{}) is called as blog.getPost.
+// Receivers implementing MethodHelper provide the descriptions of their methods.
+func (this *Server) Register(name string, rcvr interface{}) error {
+	v := reflect.ValueOf(rcvr)
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("xmlrpc: cannot register nil receiver for %s", name)
+	}
+	t := v.Type()
+	helper, _ := rcvr.(MethodHelper)
+	registered := 0
+	for i := 0; i < t.NumMethod(); i++ {
+		if t.Method(i).PkgPath != "" || helper != nil && t.Method(i).Name == "MethodHelp" {
+			continue
+		}
+		m, err := newMethod(v.Method(i))
+		if err != nil {
+			// not suitable as XML-RPC method
+			continue
+		}
+		if helper != nil {
+			m.help = helper.MethodHelp(t.Method(i).Name)
+		}
+		this.add(name+"."+lowerFirst(t.Method(i).Name), m)
+		registered++
+	}
+	if registered == 0 {
+		return fmt.Errorf("xmlrpc: type %s has no exported methods of suitable type", t)
+	}
+	return nil
+}
+
+// RegisterFunc publishes the function fn as the method name.
+func (this *Server) RegisterFunc(name string, fn interface{}, opts ...MethodOption) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("xmlrpc: cannot register %T as function %s", fn, name)
+	}
+	m, err := newMethod(v)
+	if err != nil {
+		return fmt.Errorf("xmlrpc: cannot register function %s: %v", name, err)
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	this.add(name, m)
+	return nil
+}
+
+func (this *Server) add(name string, m *method) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.methods == nil {
+		this.methods = make(map[string]*method)
+	}
+	this.methods[name] = m
+}
+
+func newMethod(fn reflect.Value) (*method, error) {
+	t := fn.Type()
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("variadic function %s is not supported", t)
+	}
+	m := &method{fn: fn}
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && t.In(i) == contextType {
+			m.hasCtx = true
+			continue
+		}
+		m.args = append(m.args, t.In(i))
+	}
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		m.hasErr = t.Out(0) == errorType
+		m.hasResult = !m.hasErr
+	case 2:
+		if t.Out(1) != errorType {
+			return nil, fmt.Errorf("second result of %s is not error", t)
+		}
+		m.hasResult, m.hasErr = true, true
+	default:
+		return nil, fmt.Errorf("function %s has too many results", t)
+	}
+	return m, nil
+}
+
+func (this *Server) lookup(name string) *method {
+	this.mu.RLock()
+	m := this.methods[name]
+	this.mu.RUnlock()
+	if m == nil {
+		return this.builtin(name)
+	}
+	return m
+}
+
+// ServeHTTP decodes the <methodCall> of a POST request, calls the
+// registered function and answers with its result or a fault.
+func (this *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result interface{}
+	d := NewDecoder(r.Body)
+	d.Limits = DefaultLimits
+	if this.Limits != nil {
+		d.Limits = *this.Limits
+	}
+	name, err := d.DecodeCall()
+	if errors.Is(err, ErrLimitExceeded) {
+		err = &Fault{Code: FaultInvalidRequest, String: fmt.Sprintf("request too large: %v", err)}
+	} else if err != nil {
+		err = &Fault{Code: FaultParseError, String: fmt.Sprintf("cannot parse method call: %v", err)}
+	} else {
+		result, err = this.call(r.Context(), name, d.decodeCallParams)
+	}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	if err == nil {
+		if err = e.EncodeResponse(result); err != nil {
+			// the fault replaces the partial response
+			buf.Reset()
+			e = NewEncoder(buf)
+			err = &Fault{Code: FaultInternalError, String: fmt.Sprintf("cannot encode result: %v", err)}
+		}
+	}
+	if err != nil {
+		e.EncodeFault(faultOf(err))
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+// call looks up the method name, decodes its arguments with decode and calls it.
+// decode receives the argument values and returns the number of params found.
+func (this *Server) call(ctx context.Context, name string, decode func([]reflect.Value) (int, error)) (result interface{}, err error) {
+	m := this.lookup(name)
+	if m == nil && name == multicallMethod {
+		return this.multicall(ctx, decode)
+	}
+	if m == nil {
+		return nil, &Fault{Code: FaultMethodNotFound, String: fmt.Sprintf("method %s not found", name)}
+	}
+
+	args := make([]reflect.Value, len(m.args))
+	for i, t := range m.args {
+		args[i] = reflect.New(t).Elem()
+	}
+	if err := decodeArgs(name, args, decode); err != nil {
+		return nil, err
+	}
+	if m.hasCtx {
+		args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &Fault{Code: FaultInternalError, String: fmt.Sprintf("method %s panicked: %v", name, r)}
+		}
+	}()
+	out := m.fn.Call(args)
+	if m.hasErr {
+		if e := out[len(out)-1]; !e.IsNil() {
+			return nil, e.Interface().(error)
+		}
+	}
+	if m.hasResult {
+		result = out[0].Interface()
+	}
+	return result, nil
+}
+
+// decodeArgs decodes exactly len(args) params of the method name into args.
+func decodeArgs(name string, args []reflect.Value, decode func([]reflect.Value) (int, error)) error {
+	n, err := decode(args)
+	if err != nil {
+		var (
+			typeErr *TypeError
+			fault   *Fault
+		)
+		if errors.As(err, &fault) {
+			return fault
+		}
+		if errors.Is(err, ErrLimitExceeded) {
+			return &Fault{Code: FaultInvalidRequest, String: fmt.Sprintf("request too large: %v", err)}
+		}
+		if errors.As(err, &typeErr) {
+			return &Fault{Code: FaultInvalidParams, String: fmt.Sprintf("invalid params for %s: %v", name, err)}
+		}
+		return &Fault{Code: FaultParseError, String: fmt.Sprintf("cannot parse params: %v", err)}
+	}
+	if n != len(args) {
+		return &Fault{Code: FaultInvalidParams, String: fmt.Sprintf("method %s expects %d params but got %d", name, len(args), n)}
+	}
+	return nil
+}
+
+// faultOf returns the fault code and string answered for err.
+func faultOf(err error) (int, string) {
+	var fault *Fault
+	if errors.As(err, &fault) {
+		return fault.Code, fault.String
+	}
+	return FaultApplicationError, err.Error()
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
@@ -0,0 +1,125 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type post struct {
+	ID    int    `xmlrpc:"postId"`
+	Title string `xmlrpc:"title"`
+}
+
+type blogService struct {
+	posts map[int]post
+}
+
+func (this *blogService) GetPost(id int) (post, error) {
+	if p, ok := this.posts[id]; ok {
+		return p, nil
+	}
+	return post{}, &Fault{Code: 404, String: "no such post"}
+}
+
+func (this *blogService) CountPosts(ctx context.Context) int {
+	return len(this.posts)
+}
+
+func (this *blogService) Fail() error {
+	return errors.New("something broke")
+}
+
+func (this *blogService) unexported() int {
+	return 0
+}
+
+func serverCall(t *testing.T, url string, method string, result interface{}, args ...interface{}) error {
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, method, args...); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "text/xml", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/xml") {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	return UnmarshalResponse(resp.Body, result)
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Register("blog", &blogService{posts: map[int]post{1: {1, "Hello"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterFunc("math.add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterFunc("broken", 42); err == nil {
+		t.Errorf("expected error registering non function")
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var p post
+	if err := serverCall(t, ts.URL, "blog.getPost", &p, 1); err != nil || p.Title != "Hello" {
+		t.Errorf("unexpected post %+v err:%v", p, err)
+	}
+	var sum int
+	if err := serverCall(t, ts.URL, "math.add", &sum, 2, 3); err != nil || sum != 5 {
+		t.Errorf("unexpected sum %d err:%v", sum, err)
+	}
+	var count int
+	if err := serverCall(t, ts.URL, "blog.countPosts", &count); err != nil || count != 1 {
+		t.Errorf("unexpected count %d err:%v", count, err)
+	}
+
+	for _, c := range []struct {
+		method string
+		args   []interface{}
+		fault  string
+	}{
+		{"blog.getPost", []interface{}{2}, "404"},
+		{"blog.fail", nil, "something broke"},
+		{"blog.unexported", nil, "-32601"},
+		{"math.add", []interface{}{1}, "-32602"},
+		{"math.add", []interface{}{1, "two"}, "-32602"},
+	} {
+		var o interface{}
+		if err := serverCall(t, ts.URL, c.method, &o, c.args...); err == nil || !strings.Contains(err.Error(), c.fault) {
+			t.Errorf("%s: expected fault %s got %v", c.method, c.fault, err)
+		}
+	}
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestServerUnencodableResult(t *testing.T) {
+	srv := NewServer()
+	if err := srv.RegisterFunc("feed", func() chan int { return make(chan int) }); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var o interface{}
+	err := serverCall(t, ts.URL, "feed", &o)
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Code != FaultInternalError || !strings.Contains(fault.String, "cannot encode result") {
+		t.Errorf("expected fault %d got %v", FaultInternalError, err)
+	}
+}
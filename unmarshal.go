@@ -0,0 +1,300 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// A TypeError describes an XML-RPC value that cannot be stored
+// in a Go value of a specific type.
+type TypeError struct {
+	Value string       // the XML-RPC type, e.g. "int" or "struct", followed by the number on overflows
+	Type  reflect.Type // the Go type it could not be assigned to
+
+	// position of the value in the input, set by the Decoder
+	Offset int64  // input offset after the value
+	Line   int    // line of Offset, starting at 1
+	Column int    // column of Offset, starting at 1
+	Path   string // path of the value, e.g. params[0].posts[17].date
+}
+
+func (e *TypeError) Error() string {
+	return "xmlrpc: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String() + position(e.Path, e.Line, e.Column)
+}
+
+// A SyntaxError describes malformed XML or a document not matching the structure of XML-RPC.
+type SyntaxError struct {
+	Msg    string
+	Offset int64  // input offset after the token where the error was detected
+	Line   int    // line of Offset, starting at 1
+	Column int    // column of Offset, starting at 1
+	Path   string // path of the value being decoded, e.g. params[0].posts[17].date
+	Err    error  // underlying error, if any
+}
+
+func (e *SyntaxError) Error() string {
+	return "xmlrpc: syntax error" + position(e.Path, e.Line, e.Column) + ": " + e.Msg
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func position(path string, line, column int) string {
+	s := ""
+	if path != "" {
+		s = " at " + path
+	}
+	if line > 0 {
+		s += fmt.Sprintf(" (line %d, column %d)", line, column)
+	}
+	return s
+}
+
+// indirect walks down v, allocating nil pointers as needed,
+// until it reaches a value that is not a pointer.
+// Non-nil interfaces holding a pointer are followed as well.
+func indirect(v reflect.Value) reflect.Value {
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			return v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
+
+func isEmptyInterface(v reflect.Value) bool {
+	return v.Kind() == reflect.Interface && v.NumMethod() == 0
+}
+
+func setInt(o reflect.Value, i int64) error {
+	switch v := indirect(o); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(i) {
+			return &TypeError{Value: string(integerTag) + " " + strconv.FormatInt(i, 10), Type: v.Type()}
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return &TypeError{Value: string(integerTag) + " " + strconv.FormatInt(i, 10), Type: v.Type()}
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(i))
+	default:
+		if isBigNumber(v.Type()) {
+			return setBigText(v, integerTag, strconv.FormatInt(i, 10))
+		}
+		if !isEmptyInterface(v) {
+			return &TypeError{Value: string(integerTag), Type: v.Type()}
+		}
+		v.Set(reflect.ValueOf(i))
+	}
+	return nil
+}
+
+func setFloat(o reflect.Value, f float64) error {
+	switch v := indirect(o); v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if v.OverflowFloat(f) {
+			return &TypeError{Value: string(doubleTag) + " " + strconv.FormatFloat(f, 'g', -1, 64), Type: v.Type()}
+		}
+		v.SetFloat(f)
+	default:
+		if isBigNumber(v.Type()) {
+			return setBigText(v, doubleTag, strconv.FormatFloat(f, 'f', -1, 64))
+		}
+		if !isEmptyInterface(v) {
+			return &TypeError{Value: string(doubleTag), Type: v.Type()}
+		}
+		v.Set(reflect.ValueOf(f))
+	}
+	return nil
+}
+
+func setBool(o reflect.Value, b bool) error {
+	switch v := indirect(o); v.Kind() {
+	case reflect.Bool:
+		v.SetBool(b)
+	default:
+		if !isEmptyInterface(v) {
+			return &TypeError{Value: string(booleanTag), Type: v.Type()}
+		}
+		v.Set(reflect.ValueOf(b))
+	}
+	return nil
+}
+
+func setString(o reflect.Value, s string) error {
+	switch v := indirect(o); v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	default:
+		if isBigNumber(v.Type()) {
+			return setBigText(v, stringTag, s)
+		}
+		if !isEmptyInterface(v) {
+			return &TypeError{Value: string(stringTag), Type: v.Type()}
+		}
+		v.Set(reflect.ValueOf(s))
+	}
+	return nil
+}
+
+func setTime(o reflect.Value, t time.Time) error {
+	v := indirect(o)
+	if v.Type() != timeType && !isEmptyInterface(v) {
+		return &TypeError{Value: string(dateTimeTag), Type: v.Type()}
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func setBytes(o reflect.Value, b []byte) error {
+	switch v := indirect(o); {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(b)
+	case isEmptyInterface(v):
+		v.Set(reflect.ValueOf(b))
+	default:
+		return &TypeError{Value: string(base64Tag), Type: v.Type()}
+	}
+	return nil
+}
+
+// parseScalar stores the text form s of a number or boolean in o.
+func parseScalar(o reflect.Value, s string) error {
+	v := indirect(o)
+	var err error
+	switch v.Kind() {
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		return setString(v, s)
+	}
+	if err != nil {
+		return fmt.Errorf("xmlrpc: cannot unmarshal string %q into Go value of type %s: %v", s, v.Type(), err)
+	}
+	return nil
+}
+
+// typeName returns the XML-RPC type of a generically decoded value.
+func typeName(n interface{}) string {
+	switch n.(type) {
+	case int64:
+		return string(integerTag)
+	case float64:
+		return string(doubleTag)
+	case *big.Int:
+		return string(exBigIntegerTag)
+	case *big.Float:
+		return string(exBigDecimalTag)
+	case bool:
+		return string(booleanTag)
+	case string:
+		return string(stringTag)
+	case time.Time:
+		return string(dateTimeTag)
+	case []byte:
+		return string(base64Tag)
+	case []interface{}:
+		return string(arrayTag)
+	case map[string]interface{}:
+		return string(structTag)
+	}
+	return string(nilTag)
+}
+
+// readError marks errors of the underlying reader, which are returned unchanged.
+type readError struct {
+	err error
+}
+
+func (e *readError) Error() string {
+	return e.err.Error()
+}
+
+func (e *readError) Unwrap() error {
+	return e.err
+}
+
+// push appends the segment s, e.g. "params[0]", "[17]" or ".date", to the
+// path of the value being decoded. It is only removed with pop once the
+// value was decoded, so after an error the path leads to the failing value.
+func (this *Decoder) push(s string) {
+	this.path = append(this.path, s)
+}
+
+func (this *Decoder) pop() {
+	this.path = this.path[:len(this.path)-1]
+}
+
+// annotate adds the position and path of the decoder to the error *err
+// returned by decoding a document. Errors not describing the document
+// itself are left unchanged.
+func (this *Decoder) annotate(err *error) {
+	if *err == nil || *err == io.EOF {
+		return
+	}
+	path := strings.Join(this.path, "")
+	this.path = this.path[:0]
+	line, column := this.d.InputPos()
+	offset := this.d.InputOffset()
+
+	var (
+		readErr   *readError
+		typeErr   *TypeError
+		syntaxErr *SyntaxError
+		xmlErr    *xml.SyntaxError
+		fault     *Fault
+	)
+	switch {
+	case errors.As(*err, &readErr):
+		*err = readErr.err
+	case errors.As(*err, &typeErr):
+		if typeErr.Line == 0 && typeErr.Path == "" {
+			typeErr.Offset, typeErr.Line, typeErr.Column, typeErr.Path = offset, line, column, path
+		}
+	case errors.As(*err, &syntaxErr), errors.As(*err, &fault), errors.Is(*err, ErrLimitExceeded):
+	case errors.As(*err, &xmlErr):
+		*err = &SyntaxError{Msg: xmlErr.Msg, Offset: offset, Line: xmlErr.Line, Column: column, Path: path, Err: xmlErr}
+	default:
+		*err = &SyntaxError{Msg: (*err).Error(), Offset: offset, Line: line, Column: column, Path: path, Err: *err}
+	}
+}
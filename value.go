@@ -0,0 +1,354 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Kind is the XML-RPC type of a Value.
+type Kind int
+
+const (
+	InvalidKind  Kind = iota
+	UntypedKind       // text directly inside <value>, a string by the specification
+	StringKind        // <string>
+	IntKind           // <int>, <i4>, <i8> and the integer extension types
+	BooleanKind       // <boolean>
+	DoubleKind        // <double>, <ex:float> and <ex:bigdecimal>
+	DateTimeKind      // <dateTime.iso8601> and <ex:dateTime>
+	Base64Kind        // <base64>
+	NilKind           // <nil/> and <ex:nil/>
+	ArrayKind         // <array>
+	StructKind        // <struct>
+)
+
+var kindTags = map[Kind]tag{
+	StringKind:   stringTag,
+	IntKind:      integerTag,
+	BooleanKind:  booleanTag,
+	DoubleKind:   doubleTag,
+	DateTimeKind: dateTimeTag,
+	Base64Kind:   base64Tag,
+	NilKind:      nilTag,
+	ArrayKind:    arrayTag,
+	StructKind:   structTag,
+}
+
+func (k Kind) String() string {
+	switch k {
+	case InvalidKind:
+		return "invalid"
+	case UntypedKind:
+		return "untyped"
+	}
+	return string(kindTags[k])
+}
+
+// kindOf returns the kind of the type element t, which has the ex prefix for
+// extension types. It knows exactly the type elements accepted by the Decoder.
+func kindOf(t string) Kind {
+	switch t {
+	case string(integerTag), string(integerTag2), string(i8Tag),
+		string(i8Tag.ext()), string(exI1Tag.ext()), string(exI2Tag.ext()), string(exBigIntegerTag.ext()):
+		return IntKind
+	case string(stringTag):
+		return StringKind
+	case string(booleanTag):
+		return BooleanKind
+	case string(doubleTag), string(exFloatTag.ext()), string(exBigDecimalTag.ext()):
+		return DoubleKind
+	case string(dateTimeTag), string(exDateTimeTag.ext()):
+		return DateTimeKind
+	case string(base64Tag):
+		return Base64Kind
+	case string(nilTag), string(nilTag.ext()):
+		return NilKind
+	case string(arrayTag):
+		return ArrayKind
+	case string(structTag):
+		return StructKind
+	}
+	return InvalidKind
+}
+
+// A Value holds an XML-RPC value as written in the document. Decoding into
+// a Value keeps the type elements, the text of scalars including white space
+// and the order of struct members, so encoding it writes an equivalent value.
+// Values with extension types need an Encoder with Extensions set to declare
+// the ex namespace.
+type Value struct {
+	Kind Kind
+	// Type is the type element as written, e.g. "i4" or "ex:i8". If it is empty
+	// the default element of Kind is written.
+	Type string
+	// Text is the text of a scalar value.
+	Text string
+	// Members are the members of a struct in document order.
+	Members []Member
+	// Elems are the elements of an array.
+	Elems []Value
+}
+
+// A Member is a member of a struct Value.
+type Member struct {
+	Name  string
+	Value Value
+}
+
+// Int returns the value of an IntKind value.
+func (this *Value) Int() (int64, error) {
+	if this.Kind != IntKind {
+		return 0, &TypeError{Value: this.Kind.String(), Type: reflect.TypeOf(int64(0))}
+	}
+	return strconv.ParseInt(strings.TrimSpace(this.Text), 10, 64)
+}
+
+// Float returns the value of a DoubleKind or IntKind value.
+func (this *Value) Float() (float64, error) {
+	if this.Kind != DoubleKind && this.Kind != IntKind {
+		return 0, &TypeError{Value: this.Kind.String(), Type: reflect.TypeOf(float64(0))}
+	}
+	return strconv.ParseFloat(strings.TrimSpace(this.Text), 64)
+}
+
+// Bool returns the value of a BooleanKind value.
+func (this *Value) Bool() (bool, error) {
+	if this.Kind != BooleanKind {
+		return false, &TypeError{Value: this.Kind.String(), Type: reflect.TypeOf(false)}
+	}
+	switch s := strings.TrimSpace(this.Text); s {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("xmlrpc: invalid boolean %q", s)
+	}
+}
+
+// Time returns the value of a DateTimeKind value, times without zone are in UTC.
+func (this *Value) Time() (time.Time, error) {
+	if this.Kind != DateTimeKind {
+		return time.Time{}, &TypeError{Value: this.Kind.String(), Type: timeType}
+	}
+	return parseDateTime(this.Text, nil)
+}
+
+// Bytes returns the decoded data of a Base64Kind value.
+func (this *Value) Bytes() ([]byte, error) {
+	if this.Kind != Base64Kind {
+		return nil, &TypeError{Value: this.Kind.String(), Type: reflect.TypeOf([]byte(nil))}
+	}
+	return base64.StdEncoding.DecodeString(strings.Map(dropSpace, this.Text))
+}
+
+// Member returns the value of the first struct member called name.
+func (this *Value) Member(name string) (*Value, bool) {
+	for i := range this.Members {
+		if this.Members[i].Name == name {
+			return &this.Members[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+func (this Value) MarshalXMLRPC(e *Encoder) error {
+	if this.Kind == UntypedKind {
+		e.writeText(this.Text)
+		return e.err
+	}
+	if _, ok := kindTags[this.Kind]; !ok {
+		return fmt.Errorf("xmlrpc: cannot encode Value of invalid kind %d", this.Kind)
+	}
+	t := tag(this.Type)
+	if t == "" {
+		t = kindTags[this.Kind]
+	} else if kindOf(this.Type) != this.Kind {
+		// Type is written as element name and must be one of the known types
+		return fmt.Errorf("xmlrpc: cannot encode Value of kind %s with type %q", this.Kind, this.Type)
+	}
+	switch this.Kind {
+	case NilKind:
+		e.openCloseTag(t)
+	case ArrayKind:
+		e.openTag(t)
+		e.openTag(dataTag)
+		for _, elem := range this.Elems {
+			e.openTag(valueTag)
+			if err := elem.MarshalXMLRPC(e); err != nil {
+				return err
+			}
+			e.closeTag(valueTag)
+		}
+		e.closeTag(dataTag)
+		e.closeTag(t)
+	case StructKind:
+		e.openTag(t)
+		for _, m := range this.Members {
+			e.openTag(memberTag)
+			e.openTag(nameTag)
+			e.writeText(m.Name)
+			e.closeTag(nameTag)
+			e.openTag(valueTag)
+			if err := m.Value.MarshalXMLRPC(e); err != nil {
+				return err
+			}
+			e.closeTag(valueTag)
+			e.closeTag(memberTag)
+		}
+		e.closeTag(t)
+	default:
+		e.openTag(t)
+		e.writeText(this.Text)
+		e.closeTag(t)
+	}
+	return e.err
+}
+
+func (this *Value) UnmarshalXMLRPC(d *Decoder, start xml.StartElement) error {
+	*this = Value{}
+	var text []byte
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			if text, err = d.appendText(text, t); err != nil {
+				return err
+			}
+		case xml.StartElement:
+			// text next to a type element must be whitespace like in decodeValue
+			if this.Kind != InvalidKind || len(bytes.TrimSpace(text)) > 0 {
+				return fmt.Errorf("xmlrpc: unexpected element %s in value", t.Name.Local)
+			}
+			this.Type = t.Name.Local
+			if isExtension(t.Name) {
+				this.Type = string(tag(t.Name.Local).ext())
+			}
+			if this.Kind = kindOf(this.Type); this.Kind == InvalidKind {
+				return fmt.Errorf("xmlrpc: unknown value type %s", this.Type)
+			}
+			switch this.Kind {
+			case ArrayKind:
+				err = this.decodeElems(d)
+			case StructKind:
+				err = this.decodeMembers(d)
+			default:
+				this.Text, err = d.readText()
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			if this.Kind == InvalidKind {
+				this.Kind, this.Text = UntypedKind, string(text)
+			} else if len(bytes.TrimSpace(text)) > 0 {
+				return fmt.Errorf("xmlrpc: unexpected text %q in value", bytes.TrimSpace(text))
+			}
+			return nil
+		}
+	}
+}
+
+// decodeElems decodes the <data> of an array.
+func (this *Value) decodeElems(d *Decoder) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case string(dataTag):
+				// continue
+			case string(valueTag):
+				if err := d.checkMembers(len(this.Elems) + 1); err != nil {
+					return err
+				}
+				this.Elems = append(this.Elems, Value{})
+				d.push(fmt.Sprintf("[%d]", len(this.Elems)-1))
+				if err := d.DecodeElement(&this.Elems[len(this.Elems)-1], &t); err != nil {
+					return err
+				}
+				d.pop()
+			default:
+				return fmt.Errorf("xmlrpc: unexpected element %s in array", t.Name.Local)
+			}
+		case xml.EndElement:
+			if t.Name.Local == string(arrayTag) {
+				return nil
+			}
+		}
+	}
+}
+
+// decodeMembers decodes the members of a struct.
+func (this *Value) decodeMembers(d *Decoder) error {
+	var m *Member
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case string(memberTag):
+				if err := d.checkMembers(len(this.Members) + 1); err != nil {
+					return err
+				}
+				this.Members = append(this.Members, Member{})
+				m = &this.Members[len(this.Members)-1]
+			case string(nameTag), string(valueTag):
+				if m == nil {
+					return fmt.Errorf("xmlrpc: %s outside of member", t.Name.Local)
+				}
+				if t.Name.Local == string(valueTag) {
+					d.push("." + m.Name)
+					if err := d.DecodeElement(&m.Value, &t); err != nil {
+						return err
+					}
+					d.pop()
+				} else if m.Name, err = d.readText(); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("xmlrpc: unexpected element %s in struct", t.Name.Local)
+			}
+		case xml.EndElement:
+			if t.Name.Local == string(structTag) {
+				return nil
+			}
+		}
+	}
+}
+
+// readText reads the text of the current element up to and including its end element.
+func (this *Decoder) readText() (string, error) {
+	var text []byte
+	for {
+		t, err := this.d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch v := t.(type) {
+		case xml.CharData:
+			if text, err = this.appendText(text, v); err != nil {
+				return "", err
+			}
+		case xml.StartElement:
+			return "", fmt.Errorf("xmlrpc: unexpected element %s in text", v.Name.Local)
+		case xml.EndElement:
+			return string(text), nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	value := `<value><struct>` +
+		`<member><name>z</name><value><i4>1</i4></value></member>` +
+		`<member><name>a</name><value><int> 2 </int></value></member>` +
+		`<member><name>untyped</name><value>  text  </value></member>` +
+		`<member><name>string</name><value><string>a &amp; b</string></value></member>` +
+		`<member><name>nil</name><value><nil/></value></member>` +
+		`<member><name>big</name><value><ex:i8>9223372036854775807</ex:i8></value></member>` +
+		`<member><name>list</name><value><array><data>` +
+		`<value><boolean>1</boolean></value>` +
+		`<value><double>1.50</double></value>` +
+		`<value><dateTime.iso8601>1998-07-17T14:08:55Z</dateTime.iso8601></value>` +
+		`<value><base64>eW91IGNhbid0IHJlYWQgdGhpcyE=</base64></value>` +
+		`<value><ex:nil/></value>` +
+		`</data></array></value></member>` +
+		`</struct></value>`
+	s := `<?xml version="1.0"?><methodResponse xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"><params><param>` + value + `</param></params></methodResponse>`
+
+	var v Value
+	if err := UnmarshalResponse(strings.NewReader(s), &v); err != nil {
+		t.Fatalf("error unmarshaling err:%v", err)
+	}
+	if v.Kind != StructKind || len(v.Members) != 7 || v.Members[0].Name != "z" || v.Members[1].Value.Type != "int" {
+		t.Errorf("unexpected value %+v", v)
+	}
+	if i, err := v.Members[1].Value.Int(); err != nil || i != 2 {
+		t.Errorf("unexpected int %d err:%v", i, err)
+	}
+	if u, ok := v.Member("untyped"); !ok || u.Kind != UntypedKind || u.Text != "  text  " {
+		t.Errorf("unexpected untyped value %+v", u)
+	}
+	if big, _ := v.Member("big"); big.Kind != IntKind || big.Type != "ex:i8" {
+		t.Errorf("unexpected extension value %+v", big)
+	}
+	list, _ := v.Member("list")
+	if b, err := list.Elems[0].Bool(); err != nil || !b {
+		t.Errorf("unexpected boolean %t err:%v", b, err)
+	}
+	if f, err := list.Elems[1].Float(); err != nil || f != 1.5 {
+		t.Errorf("unexpected double %f err:%v", f, err)
+	}
+	if tim, err := list.Elems[2].Time(); err != nil || !tim.Equal(time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC)) {
+		t.Errorf("unexpected time %v err:%v", tim, err)
+	}
+	if data, err := list.Elems[3].Bytes(); err != nil || string(data) != "you can't read this!" {
+		t.Errorf("unexpected data %q err:%v", data, err)
+	}
+	if list.Elems[4].Kind != NilKind || list.Elems[4].Type != "ex:nil" {
+		t.Errorf("unexpected nil %+v", list.Elems[4])
+	}
+	if _, err := list.Elems[0].Int(); err == nil {
+		t.Errorf("expected error for boolean as int")
+	}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.Extensions = true
+	if err := e.EncodeResponse(v); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<param>"+value+"</param>") {
+		t.Errorf("expected %s in %s", value, buf.String())
+	}
+
+	// a constructed value writes the default elements
+	buf.Reset()
+	if err := MarshalResponse(buf, Value{Kind: ArrayKind, Elems: []Value{{Kind: IntKind, Text: "1"}, {Kind: StringKind}}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<array><data><value><int>1</int></value><value><string></string></value></data></array>"; !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %s in %s", expected, buf.String())
+	}
+}
+
+func TestValueMarshalInvalidType(t *testing.T) {
+	for _, v := range []Value{
+		{Kind: StringKind, Type: "x><evil/"},
+		{Kind: IntKind, Type: "string"},
+		{Kind: InvalidKind, Type: "x"},
+		{},
+		{Kind: Kind(99)},
+		{Kind: IntKind, Type: "ex:int", Text: "1"},
+		{Kind: IntKind, Type: "ex:i4", Text: "1"},
+		{Kind: IntKind, Type: "i1", Text: "1"},
+	} {
+		buf := new(bytes.Buffer)
+		if err := Marshal(buf, "test", v); err == nil {
+			t.Errorf("%+v: expected error got %s", v, buf.String())
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Marshal(buf, "test", Value{Kind: IntKind, Type: "i4", Text: "1"}); err != nil || !strings.Contains(buf.String(), "<i4>1</i4>") {
+		t.Errorf("unexpected output %s err:%v", buf.String(), err)
+	}
+
+	// every accepted extension type is decoded again
+	for _, typ := range []string{"ex:i1", "ex:i2", "ex:i8", "ex:biginteger"} {
+		buf.Reset()
+		e := NewEncoder(buf)
+		e.Extensions = true
+		if err := e.EncodeResponse(Value{Kind: IntKind, Type: typ, Text: "7"}); err != nil {
+			t.Fatal(err)
+		}
+		var i int
+		if err := UnmarshalResponse(buf, &i); err != nil || i != 7 {
+			t.Errorf("%s: unexpected int %d err:%v", typ, i, err)
+		}
+	}
+}
+
+func TestValueUnmarshalMixedContent(t *testing.T) {
+	for _, param := range []string{
+		"<value>abc<int>1</int></value>",
+		"<value><int>1</int>abc</value>",
+	} {
+		doc := "<methodResponse><params><param>" + param + "</param></params></methodResponse>"
+		var v Value
+		if err := UnmarshalResponse(strings.NewReader(doc), &v); err == nil {
+			t.Errorf("%s: expected error got %+v", param, v)
+		}
+		var i int
+		if err := UnmarshalResponse(strings.NewReader(doc), &i); err == nil {
+			t.Errorf("%s: expected error of decodeValue", param)
+		}
+	}
+
+	doc := "<methodResponse><params><param><value>\n  <int>1</int>\n</value></param></params></methodResponse>"
+	var v Value
+	if err := UnmarshalResponse(strings.NewReader(doc), &v); err != nil || v.Kind != IntKind || v.Text != "1" {
+		t.Errorf("unexpected value %+v err:%v", v, err)
+	}
+}